@@ -0,0 +1,184 @@
+// Package blocctest provides a fake blocc.Runner for testing code that runs
+// checks, without starting processes.
+package blocctest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+)
+
+// Response describes how a faked command behaves.
+type Response struct {
+	Stdout string
+	Stderr string
+	// ExitCode is the exit code of the command. Signal names the signal that
+	// killed it, if any.
+	ExitCode int
+	Signal   string
+	// Delay is how long the command runs. Block makes it run until its context
+	// is done, e.g. because it timed out or the run stopped.
+	Delay time.Duration
+	Block bool
+	// Err makes the command fail to start, like a missing executable.
+	Err error
+}
+
+// Call records a command run by a Runner.
+type Call struct {
+	// Command is the command line, the arguments joined by spaces.
+	Command string
+	Dir     string
+	Env     []string
+	Stdin   string
+	// Killed reports whether the command was still running when its context was done.
+	Killed bool
+}
+
+// Runner is a blocc.Runner that answers commands from a script. Commands
+// without a scripted response fail to start, as if they did not exist. It is
+// safe for concurrent use.
+type Runner struct {
+	mu        sync.Mutex
+	responses map[string][]Response
+	fallback  *Response
+	calls     []Call
+	running   int
+	peak      int
+}
+
+var _ blocc.Runner = (*Runner)(nil)
+
+// NewRunner returns a runner without any scripted command.
+func NewRunner() *Runner {
+	return &Runner{responses: make(map[string][]Response)}
+}
+
+// On scripts the responses to the command line command. Successive runs of
+// the command get successive responses and the last one repeats, so that
+// On(cmd, failure, success) fakes a flaky command. It returns the runner.
+func (r *Runner) On(command string, responses ...Response) *Runner {
+	if len(responses) == 0 {
+		responses = []Response{{}}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses[command] = responses
+	return r
+}
+
+// Default sets the response to commands without a scripted response and
+// returns the runner.
+func (r *Runner) Default(response Response) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = &response
+	return r
+}
+
+// Calls returns the commands run so far, in the order they started.
+func (r *Runner) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Commands returns the command lines run so far, in the order they started.
+func (r *Runner) Commands() []string {
+	var commands []string
+	for _, call := range r.Calls() {
+		commands = append(commands, call.Command)
+	}
+	return commands
+}
+
+// MaxConcurrent returns the largest number of commands that ran at once.
+func (r *Runner) MaxConcurrent() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.peak
+}
+
+func (r *Runner) Run(ctx context.Context, p blocc.Process) (blocc.ExitStatus, error) {
+	call := Call{Command: strings.Join(p.Args, " "), Dir: p.Dir, Env: p.Env}
+	if p.Stdin != nil {
+		stdin, err := io.ReadAll(p.Stdin)
+		if err != nil {
+			return blocc.ExitStatus{}, err
+		}
+		call.Stdin = string(stdin)
+	}
+
+	response, index := r.start(call)
+	defer r.finish()
+
+	if response.Err != nil {
+		return blocc.ExitStatus{}, response.Err
+	}
+	status := blocc.ExitStatus{Executable: "/fake/bin/" + p.Args[0]}
+	if err := write(p.Stdout, response.Stdout); err != nil {
+		return status, err
+	}
+	if err := write(p.Stderr, response.Stderr); err != nil {
+		return status, err
+	}
+
+	var done <-chan time.Time
+	if !response.Block {
+		done = time.After(response.Delay)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.calls[index].Killed = true
+		r.mu.Unlock()
+		status.Code, status.Signal = -1, "killed"
+		return status, nil
+	}
+
+	status.Code, status.Signal = response.ExitCode, response.Signal
+	return status, nil
+}
+
+// start records call and returns its response and index in calls.
+func (r *Runner) start(call Call) (Response, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+	r.running++
+	r.peak = max(r.peak, r.running)
+
+	responses, ok := r.responses[call.Command]
+	switch {
+	case ok:
+		r.responses[call.Command] = responses[min(1, len(responses)-1):]
+		return responses[0], len(r.calls) - 1
+	case r.fallback != nil:
+		return *r.fallback, len(r.calls) - 1
+	default:
+		err := fmt.Errorf("blocctest: no response for %q: %w", call.Command, exec.ErrNotFound)
+		return Response{Err: err}, len(r.calls) - 1
+	}
+}
+
+func (r *Runner) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running--
+}
+
+func write(w io.Writer, s string) error {
+	if w == nil || s == "" {
+		return nil
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
@@ -0,0 +1,97 @@
+package blocctest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/blocctest"
+)
+
+func TestRunner(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("make test", blocctest.Response{Stderr: "FAIL\n", ExitCode: 1}, blocctest.Response{Stdout: "ok\n"}).
+		On("make lint")
+
+	tests := []struct {
+		args       []string
+		wantStatus blocc.ExitStatus
+		wantStdout string
+		wantStderr string
+		wantErr    error
+	}{
+		{args: []string{"make", "test"}, wantStatus: blocc.ExitStatus{Code: 1}, wantStderr: "FAIL\n"},
+		{args: []string{"make", "test"}, wantStdout: "ok\n"},
+		{args: []string{"make", "test"}, wantStdout: "ok\n"},
+		{args: []string{"make", "lint"}},
+		{args: []string{"make", "docs"}, wantErr: exec.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		status, err := runner.Run(context.Background(), blocc.Process{Args: tt.args, Stdout: &stdout, Stderr: &stderr})
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("Run(%v) error = %v, want %v", tt.args, err, tt.wantErr)
+		}
+		status.Executable = ""
+		if status != tt.wantStatus || stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+			t.Errorf("Run(%v) = %+v, stdout %q, stderr %q", tt.args, status, stdout.String(), stderr.String())
+		}
+	}
+
+	want := []string{"make test", "make test", "make test", "make lint", "make docs"}
+	if got := runner.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands() = %v, want %v", got, want)
+	}
+}
+
+func TestRunnerDefaultAndCalls(t *testing.T) {
+	runner := blocctest.NewRunner().Default(blocctest.Response{ExitCode: 3})
+
+	process := blocc.Process{Args: []string{"cat"}, Dir: "/repo", Env: []string{"A=1"}, Stdin: strings.NewReader("input")}
+	status, err := runner.Run(context.Background(), process)
+	if err != nil || status.Code != 3 {
+		t.Fatalf("Run() = %+v, %v, want exit code 3", status, err)
+	}
+
+	want := []blocctest.Call{{Command: "cat", Dir: "/repo", Env: []string{"A=1"}, Stdin: "input"}}
+	if got := runner.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunnerBlockAndDelay(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("serve", blocctest.Response{Block: true}).
+		On("build", blocctest.Response{Delay: 50 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	status, err := runner.Run(ctx, blocc.Process{Args: []string{"serve"}})
+	if err != nil || status.Code != -1 || status.Signal != "killed" {
+		t.Errorf("Run(serve) = %+v, %v, want killed", status, err)
+	}
+	if calls := runner.Calls(); !calls[0].Killed {
+		t.Errorf("Calls() = %+v, want killed call", calls)
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runner.Run(context.Background(), blocc.Process{Args: []string{"build"}})
+		}()
+	}
+	wg.Wait()
+	if got := runner.MaxConcurrent(); got != 3 {
+		t.Errorf("MaxConcurrent() = %d, want 3", got)
+	}
+}
@@ -0,0 +1,274 @@
+package blocc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxAge is how long cached results are kept by default.
+const DefaultCacheMaxAge = 7 * 24 * time.Hour
+
+// Cache stores successful results of checks with inputs, keyed on a hash of
+// the command, its environment and the content of its input files.
+type Cache struct {
+	Dir string
+}
+
+type cacheEntry struct {
+	StoredAt time.Time `json:"storedAt"`
+	Result   Result    `json:"result"`
+}
+
+// DefaultCache returns a cache in the user cache directory, e.g. ~/.cache/blocc.
+func DefaultCache() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine cache directory: %w", err)
+	}
+	return &Cache{Dir: filepath.Join(dir, "blocc")}, nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Lookup returns the result stored under key.
+func (c *Cache) Lookup(key string) (Result, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return Result{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Result{}, false
+	}
+	return entry.Result, true
+}
+
+// Store saves result under key.
+func (c *Cache) Store(key string, result Result) error {
+	data, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if err := os.WriteFile(c.path(key), data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Prune removes entries stored more than maxAge ago and returns how many were
+// removed. A zero maxAge removes every entry.
+func (c *Cache) Prune(maxAge time.Duration) (int, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || (maxAge > 0 && info.ModTime().After(cutoff)) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.Dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// cacheKey hashes everything that determines the outcome of check: its
+// command, directory, environment and output settings, and the paths and
+// content of the files matching its inputs.
+func cacheKey(check Check, env []string) (string, error) {
+	dir := workingDir(check.Dir)
+	files, err := expandInputs(dir, check.Inputs)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "command\x00%s\x00dir\x00%s\x00", check.Command, dir)
+	fmt.Fprintf(h, "stdout\x00%t\x00noStderr\x00%t\x00raw\x00%t\x00", check.IncludeStdout, check.NoStderr, check.Raw)
+	// The exit code mapping, severity and timeout decide whether a run passes.
+	fmt.Fprintf(h, "success\x00%v\x00warn\x00%v\x00severity\x00%s\x00timeout\x00%d\x00",
+		check.SuccessCodes, check.WarnCodes, check.Severity, check.Timeout)
+	fmt.Fprintf(h, "stdin\x00%s\x00", check.Stdin)
+	if check.Stdin != "" && check.Stdin != StdinPayload {
+		path := check.Stdin
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if err := hashFile(h, path); err != nil {
+			return "", err
+		}
+	}
+	for _, kv := range env {
+		fmt.Fprintf(h, "env\x00%s\x00", kv)
+	}
+
+	for _, file := range files {
+		fmt.Fprintf(h, "file\x00%s\x00", file)
+		if err := hashFile(h, filepath.Join(dir, file)); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to hash input: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	fileHash := sha256.New()
+	if _, err := io.Copy(fileHash, f); err != nil {
+		return fmt.Errorf("failed to hash input: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%x\x00", fileHash.Sum(nil))
+	return err
+}
+
+// ValidateGlob checks the syntax of an input pattern.
+func ValidateGlob(pattern string) error {
+	if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+		return fmt.Errorf("invalid input pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// expandInputs returns the sorted slash-separated paths, relative to dir, of
+// the regular files matching any of patterns. A "**" path segment matches any
+// number of directories; such patterns search the directories below their
+// literal leading directories, skipping .git.
+func expandInputs(dir string, patterns []string) ([]string, error) {
+	matched := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+
+		if !strings.Contains(pattern, "**") {
+			paths, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand input %q: %w", pattern, err)
+			}
+			for _, p := range paths {
+				if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+					rel, _ := filepath.Rel(dir, p)
+					matched[filepath.ToSlash(rel)] = true
+				}
+			}
+			continue
+		}
+
+		root := filepath.Join(dir, filepath.FromSlash(globRoot(pattern)))
+
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			if rel = filepath.ToSlash(rel); matchGlob(pattern, rel) {
+				matched[rel] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand input %q: %w", pattern, err)
+		}
+	}
+
+	files := make([]string, 0, len(matched))
+	for file := range matched {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// globRoot returns the leading directories of pattern that contain no wildcards.
+func globRoot(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	var root []string
+	for _, segment := range segments[:len(segments)-1] {
+		if segment == "**" || strings.ContainsAny(segment, `*?[\`) {
+			break
+		}
+		root = append(root, segment)
+	}
+	return strings.Join(root, "/")
+}
+
+// matchGlob reports whether the slash-separated name matches pattern, where
+// "**" matches zero or more path segments.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,209 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "go.mod", name: "go.mod", want: true},
+		{pattern: "*.go", name: "main.go", want: true},
+		{pattern: "*.go", name: "pkg/main.go", want: false},
+		{pattern: "**/*.go", name: "main.go", want: true},
+		{pattern: "**/*.go", name: "a/b/c.go", want: true},
+		{pattern: "src/**", name: "src/a/b.ts", want: true},
+		{pattern: "src/**/test_*.py", name: "src/x/test_a.py", want: true},
+		{pattern: "src/**/test_*.py", name: "lib/test_a.py", want: false},
+		{pattern: "[", name: "[", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":          "module x",
+		"main.go":         "package main",
+		"pkg/a/a.go":      "package a",
+		"pkg/a/a.txt":     "text",
+		".git/hooks/x.go": "ignored",
+	})
+
+	got, err := expandInputs(dir, []string{"go.mod", "./**/*.go", "missing/**/*.go", "*.md"})
+	if err != nil {
+		t.Fatalf("expandInputs() error = %v", err)
+	}
+
+	want := []string{"go.mod", "main.go", "pkg/a/a.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandInputs() = %v, want %v", got, want)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.go": "package main"})
+
+	check := Check{Command: "go build", Dir: dir, Inputs: []string{"*.go"}}
+	key := func(check Check, env ...string) string {
+		t.Helper()
+		k, err := cacheKey(check, env)
+		if err != nil {
+			t.Fatalf("cacheKey() error = %v", err)
+		}
+		return k
+	}
+
+	base := key(check)
+	if key(check) != base {
+		t.Error("cacheKey() is not stable")
+	}
+
+	if key(check, "GOOS=linux") == base {
+		t.Error("cacheKey() ignores the environment")
+	}
+
+	other := check
+	other.Command = "go vet"
+	if key(other) == base {
+		t.Error("cacheKey() ignores the command")
+	}
+
+	for name, change := range map[string]func(c *Check){
+		"success codes": func(c *Check) { c.SuccessCodes = []int{1} },
+		"warn codes":    func(c *Check) { c.WarnCodes = []int{1} },
+		"severity":      func(c *Check) { c.Severity = SeverityWarn },
+		"timeout":       func(c *Check) { c.Timeout = time.Minute },
+		"stdin":         func(c *Check) { c.Stdin = StdinPayload },
+	} {
+		other := check
+		change(&other)
+		if key(other) == base {
+			t.Errorf("cacheKey() ignores the %s", name)
+		}
+	}
+
+	writeFiles(t, dir, map[string]string{"input.json": "{}"})
+	withStdin := check
+	withStdin.Stdin = "input.json"
+	stdinKey := key(withStdin)
+	writeFiles(t, dir, map[string]string{"input.json": `{"changed":true}`})
+	if key(withStdin) == stdinKey {
+		t.Error("cacheKey() ignores the content of the stdin file")
+	}
+
+	writeFiles(t, dir, map[string]string{"main.go": "package main // changed"})
+	if key(check) == base {
+		t.Error("cacheKey() ignores input content")
+	}
+}
+
+func TestExecutorCache(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"input.txt": "v1"})
+	counter := filepath.Join(dir, "runs")
+
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	executor := NewExecutor(false, "", "", false).WithCache(cache)
+	check := Check{Command: "sh " + writeScript(t, dir, "echo run >> "+counter), Dir: dir, Inputs: []string{"*.txt"}}
+
+	statuses := func() Status {
+		results, _ := executor.ExecuteChecksSequential([]Check{check})
+		return results[0].Status
+	}
+
+	if got := statuses(); got != StatusPassed {
+		t.Errorf("first run status = %q, want passed", got)
+	}
+	if got := statuses(); got != StatusCached {
+		t.Errorf("second run status = %q, want cached", got)
+	}
+
+	writeFiles(t, dir, map[string]string{"input.txt": "v2"})
+	if got := statuses(); got != StatusPassed {
+		t.Errorf("run after input change status = %q, want passed", got)
+	}
+
+	runs, _ := os.ReadFile(counter)
+	if string(runs) != "run\nrun\n" {
+		t.Errorf("command ran %q, want twice", runs)
+	}
+
+	failing := Check{Command: "false", Dir: dir, Inputs: []string{"*.txt"}}
+	for range 2 {
+		if results, _ := executor.ExecuteChecksSequential([]Check{failing}); results[0].Status != StatusFailed {
+			t.Errorf("failing check status = %q, want failed every time", results[0].Status)
+		}
+	}
+
+	removed, err := cache.Prune(time.Hour)
+	if err != nil || removed != 0 {
+		t.Errorf("Prune(1h) = %d, %v, want nothing removed", removed, err)
+	}
+	removed, err = cache.Prune(0)
+	if err != nil || removed != 2 {
+		t.Errorf("Prune(0) = %d, %v, want 2 removed", removed, err)
+	}
+}
+
+func TestExecutorCacheAcrossSessions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"input.txt": "v1"})
+	cache := &Cache{Dir: filepath.Join(t.TempDir(), "cache")}
+	check := Check{Command: "true", Dir: dir, Inputs: []string{"*.txt"}}
+
+	for i, session := range []string{"s1", "s1", "s2"} {
+		data := `{"session_id":"` + session + `","hook_event_name":"PostToolUse",` +
+			`"tool_input":{"file_path":"/` + session + `.go"}}`
+		payload, err := ReadHookPayload(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		executor := NewExecutor(false, "", "", false).WithCache(cache).WithHookPayload(payload)
+		results, _ := executor.ExecuteChecksSequential([]Check{check})
+
+		want := StatusCached
+		if i == 0 {
+			want = StatusPassed
+		}
+		if results[0].Status != want {
+			t.Errorf("run %d in session %s status = %q, want %q", i+1, session, results[0].Status, want)
+		}
+	}
+}
+
+func writeScript(t *testing.T, dir, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, "script.sh")
+	if err := os.WriteFile(path, []byte(body+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,160 @@
+package blocc
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Default limits of a Capture.
+const (
+	DefaultCaptureLimit = 4 << 20
+	DefaultSpillLimit   = 1 << 30
+)
+
+// Capture records one output stream of a command within a memory limit. Up to
+// Limit bytes are kept in memory. Beyond that only the first and last Limit/2
+// bytes are kept, and the whole output spills to a temporary file of at most
+// SpillLimit bytes so that filters can still read all of it.
+type Capture struct {
+	limit      int64
+	spillLimit int64
+
+	head []byte
+	// tail is a ring buffer of the bytes after head; next is its oldest byte.
+	tail []byte
+	next int
+
+	total   int64
+	spill   *os.File
+	spilled int64
+	err     error
+}
+
+// NewCapture returns a capture keeping at most limit bytes in memory and
+// spilling at most spillLimit bytes to disk. A spillLimit of 0 disables spilling.
+func NewCapture(limit, spillLimit int64) *Capture {
+	return &Capture{limit: max(limit, 2), spillLimit: spillLimit}
+}
+
+// Write records p. It never fails, so that a full disk cannot kill the command;
+// spill errors are returned by Reader.
+func (c *Capture) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.total+int64(n) > c.limit && c.spill == nil && c.err == nil && c.spillLimit > 0 {
+		// Nothing was dropped yet, so head and tail hold the whole output so far.
+		c.startSpill()
+	}
+	c.total += int64(n)
+	c.writeSpill(p)
+
+	if room := int(c.limit/2) - len(c.head); room > 0 {
+		k := min(room, len(p))
+		c.head = append(c.head, p[:k]...)
+		p = p[k:]
+	}
+	c.writeTail(p)
+	return n, nil
+}
+
+func (c *Capture) startSpill() {
+	c.spill, c.err = os.CreateTemp("", "blocc-output-*")
+	if c.err != nil {
+		return
+	}
+	c.writeSpill(c.head)
+	c.writeSpill(c.tailBytes())
+}
+
+func (c *Capture) writeSpill(p []byte) {
+	if c.spill == nil || c.err != nil || c.spilled >= c.spillLimit {
+		return
+	}
+	p = p[:min(int64(len(p)), c.spillLimit-c.spilled)]
+	n, err := c.spill.Write(p)
+	c.spilled += int64(n)
+	c.err = err
+}
+
+func (c *Capture) writeTail(p []byte) {
+	size := int(c.limit - c.limit/2)
+	if len(p) >= size {
+		c.tail = append(c.tail[:0], p[len(p)-size:]...)
+		c.next = 0
+		return
+	}
+	if room := size - len(c.tail); room > 0 {
+		k := min(room, len(p))
+		c.tail = append(c.tail, p[:k]...)
+		p = p[k:]
+	}
+	for len(p) > 0 {
+		k := copy(c.tail[c.next:], p)
+		c.next = (c.next + k) % size
+		p = p[k:]
+	}
+}
+
+// tailBytes returns the contents of the tail ring buffer in order.
+func (c *Capture) tailBytes() []byte {
+	return append(c.tail[c.next:len(c.tail):len(c.tail)], c.tail[:c.next]...)
+}
+
+// Len returns the number of bytes written.
+func (c *Capture) Len() int64 {
+	return c.total
+}
+
+// Truncated reports whether bytes were dropped from memory.
+func (c *Capture) Truncated() bool {
+	return c.total > int64(len(c.head)+len(c.tail))
+}
+
+// Spilled reports whether the whole output can be read back from disk.
+func (c *Capture) Spilled() bool {
+	return c.spill != nil && c.err == nil && c.spilled == c.total
+}
+
+// String returns the output, or its head and tail around a truncation marker
+// when it exceeded the memory limit.
+func (c *Capture) String() string {
+	kept := string(c.head) + string(c.tailBytes())
+	if !c.Truncated() {
+		return kept
+	}
+	// Cut head and tail back to line breaks and whole UTF-8 characters.
+	head := cutHead(kept, len(c.head))
+	tail := cutTail(kept, len(c.tail))
+	return head + truncationMarker(int(c.total)-len(head)-len(tail)) + tail
+}
+
+// Reader returns a reader of the whole output, read back from disk when it
+// exceeded the memory limit. It fails when the output was truncated and did not
+// spill completely.
+func (c *Capture) Reader() (io.Reader, error) {
+	if !c.Truncated() {
+		return strings.NewReader(c.String()), nil
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !c.Spilled() {
+		return nil, errors.New("output exceeded the spill limit")
+	}
+	if _, err := c.spill.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.LimitReader(c.spill, c.spilled), nil
+}
+
+// Close removes the spill file.
+func (c *Capture) Close() error {
+	if c.spill == nil {
+		return nil
+	}
+	name := c.spill.Name()
+	c.spill.Close()
+	c.spill = nil
+	return os.Remove(name)
+}
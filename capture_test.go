@@ -0,0 +1,116 @@
+package blocc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int64
+		writes        []string
+		want          string
+		wantTruncated bool
+	}{
+		{name: "empty", limit: 16},
+		{name: "within limit", limit: 16, writes: []string{"hello ", "world\n"}, want: "hello world\n"},
+		{name: "exactly limit", limit: 8, writes: []string{"abcd", "efgh"}, want: "abcdefgh"},
+		{
+			name:          "head and tail",
+			limit:         8,
+			writes:        []string{"abc", "defgh", "ijkl", "mn"},
+			want:          "abcd" + truncationMarker(6) + "klmn",
+			wantTruncated: true,
+		},
+		{
+			name:          "write larger than tail",
+			limit:         8,
+			writes:        []string{"ab", "cdefghijklmnopqrstuvwxyz"},
+			want:          "abcd" + truncationMarker(18) + "wxyz",
+			wantTruncated: true,
+		},
+		{
+			name:          "lines",
+			limit:         16,
+			writes:        []string{"line 1\nline 2\nline 3\nline 4\n"},
+			want:          "line 1\n" + truncationMarker(14) + "line 4\n",
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCapture(tt.limit, 0)
+			total := 0
+			for _, w := range tt.writes {
+				if n, err := c.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+				total += len(w)
+			}
+
+			if got := c.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if c.Len() != int64(total) || c.Truncated() != tt.wantTruncated {
+				t.Errorf("Len() = %d, Truncated() = %v, want %d, %v", c.Len(), c.Truncated(), total, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCaptureSpill(t *testing.T) {
+	var want strings.Builder
+	c := NewCapture(1024, 1<<20)
+	for i := range 10000 {
+		line := fmt.Sprintf("line %d\n", i)
+		want.WriteString(line)
+		c.Write([]byte(line))
+	}
+
+	if !c.Truncated() || !c.Spilled() {
+		t.Fatalf("Truncated() = %v, Spilled() = %v, want both", c.Truncated(), c.Spilled())
+	}
+	got := c.String()
+	if len(got) > 1024+64 || !strings.HasPrefix(got, "line 0\n") || !strings.HasSuffix(got, "line 9999\n") {
+		t.Errorf("String() = %q, want head and tail within the limit", got)
+	}
+
+	r, err := c.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(all) != want.String() {
+		t.Errorf("Reader() returned %d bytes, want the whole %d bytes of output", len(all), want.Len())
+	}
+
+	name := c.spill.Name()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("spill file %s still exists after Close()", filepath.Base(name))
+	}
+}
+
+func TestCaptureSpillLimit(t *testing.T) {
+	c := NewCapture(16, 64)
+	defer c.Close()
+	c.Write([]byte(strings.Repeat("x", 100)))
+
+	if c.Spilled() || c.Len() != 100 {
+		t.Errorf("Spilled() = %v, Len() = %d, want partial spill of 100 bytes", c.Spilled(), c.Len())
+	}
+	if _, err := c.Reader(); err == nil || !strings.Contains(err.Error(), "spill limit") {
+		t.Errorf("Reader() error = %v, want spill limit error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package blocc
+
+import (
+	"fmt"
+	"time"
+)
+
+// Severity decides how a failing check is reported.
+type Severity string
+
+const (
+	// SeverityBlock reports the failure to Claude and blocks (exit code 2).
+	SeverityBlock Severity = "block"
+	// SeverityWarn reports the failure without blocking.
+	SeverityWarn Severity = "warn"
+	// SeverityIgnore never reports or blocks on failure.
+	SeverityIgnore Severity = "ignore"
+)
+
+// ParseSeverity parses a severity name. An empty name is SeverityBlock.
+func ParseSeverity(s string) (Severity, error) {
+	switch Severity(s) {
+	case "", SeverityBlock:
+		return SeverityBlock, nil
+	case SeverityWarn, SeverityIgnore:
+		return Severity(s), nil
+	default:
+		return "", fmt.Errorf("unknown severity %q (want block, warn or ignore)", s)
+	}
+}
+
+// StdinPayload is the Check.Stdin value that pipes the hook payload into the command.
+const StdinPayload = "payload"
+
+// Check is the specification of a single command run by the executor.
+type Check struct {
+	Name    string
+	Command string
+
+	IncludeStdout  bool
+	NoStderr       bool
+	StdoutPipeline Pipeline
+	StderrPipeline Pipeline
+
+	// Raw disables output normalization and the NormalizeEnv variables.
+	Raw bool
+
+	Timeout time.Duration
+	Env     map[string]string
+	Dir     string
+	// Stdin is StdinPayload to pipe the hook payload into the command, or the
+	// path, relative to Dir, of a file to read the command's input from.
+	Stdin string
+
+	// Severity decides how a failure is reported. The zero value blocks.
+	Severity Severity
+
+	// SuccessCodes are non-zero exit codes that count as passing, e.g. 1 for grep
+	// finding nothing. WarnCodes are exit codes reported with SeverityWarn.
+	SuccessCodes []int
+	WarnCodes    []int
+
+	// Inputs are glob patterns, relative to Dir, of the files the check depends on.
+	// Checks with inputs reuse a previous successful result while the inputs are unchanged.
+	Inputs []string
+
+	// Needs names the checks that must pass before this check runs.
+	Needs []string
+
+	// Foreach runs the check once per workspace package, of WorkspaceKind only
+	// when set, and of packages containing changed files only with ChangedOnly.
+	// See ExpandWorkspaces.
+	Foreach       bool
+	WorkspaceKind WorkspaceKind
+	ChangedOnly   bool
+	// Package is the workspace package directory of an expanded check.
+	Package string
+
+	// Retries is how many times a failing command is run again. The first
+	// retry waits RetryDelay, and every further wait is multiplied by RetryBackoff.
+	Retries      int
+	RetryDelay   time.Duration
+	RetryBackoff float64
+}
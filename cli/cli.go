@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/alecthomas/kong"
 )
@@ -25,16 +26,104 @@ func (v VersionFlag) BeforeApply(app *kong.Kong, _ kong.Vars) error {
 	return nil
 }
 
+type RunCmd struct {
+	Commands []string `arg:"" name:"commands" help:"Commands to execute" optional:""`
+}
+
+type DoctorCmd struct {
+	Commands []string `arg:"" name:"commands" help:"Commands whose executables should be checked" optional:""`
+}
+
+type HistoryCmd struct {
+	List  HistoryListCmd  `cmd:"" default:"1" help:"List recorded runs (default)"`
+	Show  HistoryShowCmd  `cmd:"" help:"Show the results of a recorded run"`
+	Stats HistoryStatsCmd `cmd:"" help:"Show per-check failure rates"`
+}
+
+type HistoryListCmd struct {
+	Limit   int    `help:"Number of most recent runs to list (0 for all)" default:"20"`
+	Session string `help:"Only list runs of this Claude Code session"`
+	Blocked bool   `help:"Only list runs that blocked"`
+}
+
+type HistoryShowCmd struct {
+	ID string `arg:"" name:"id" help:"Run ID, a unique prefix of it, or \"last\"" optional:"" default:"last"`
+}
+
+type HistoryStatsCmd struct {
+	Session string `help:"Only include runs of this Claude Code session"`
+}
+
+type WatchCmd struct {
+	Commands []string      `arg:"" name:"commands" help:"Commands to execute" optional:""`
+	Interval time.Duration `help:"How often to poll for changed files" default:"500ms"`
+	Debounce time.Duration `help:"Wait until files stop changing for this long" default:"300ms"`
+}
+
+type SimulateCmd struct {
+	Event          string `arg:"" help:"Hook event, e.g. Stop, PreToolUse or PostToolUse" default:"Stop"`
+	File           string `help:"File edited by the simulated tool call" type:"path"`
+	Tool           string `help:"Tool of PreToolUse and PostToolUse" enum:"Edit,MultiEdit,Write,Bash" default:"Edit"`
+	StopHookActive bool   `help:"Set stop_hook_active, as when Claude continues after a stop hook blocked"`
+	Prompt         string `help:"Prompt of UserPromptSubmit" default:"Fix the failing tests"`
+	Session        string `help:"Session ID in the payload" default:"simulated-session"`
+	Command        string `help:"Hook command to run instead of the hooks in .claude/settings*.json"`
+}
+
+type CacheCmd struct {
+	Prune CachePruneCmd `cmd:"" help:"Remove cached results"`
+}
+
+type CachePruneCmd struct {
+	MaxAge time.Duration `help:"Remove results cached longer ago than this (0 removes all)" default:"168h"`
+}
+
 type CLI struct {
 	Version      VersionFlag `name:"version" help:"Show version information" short:"v"`
-	Commands     []string    `arg:"" name:"commands" help:"Commands to execute" optional:""`
 	Parallel     bool        `help:"Execute commands in parallel" short:"p"`
+	Jobs         int         `help:"Maximum number of concurrent checks (default 1, unlimited with -p)" short:"j"`
 	Message      string      `help:"Custom error message" short:"m"`
 	Init         bool        `help:"Initialize settings.local.json" short:"i"`
 	Stdout       bool        `help:"Include stdout in error output" short:"s"`
 	StdoutFilter string      `help:"Filter command for stdout" short:"o"`
 	StderrFilter string      `help:"Filter command for stderr" short:"e"`
+	StdoutPipe   []string    `help:"Built-in stdout filter stage, repeatable (e.g. grep:RE, head:N, sh:CMD)" sep:"none"`
+	StderrPipe   []string    `help:"Built-in stderr filter stage, repeatable (e.g. grep:RE, head:N, sh:CMD)" sep:"none"`
 	NoStderr     bool        `help:"Exclude stderr from error output" short:"n"`
+	Format       string      `help:"Error output format" enum:"json,text,markdown,compact" default:"json" short:"f"`
+	ReportFile   string      `help:"Also write a report file (format inferred from .xml, .sarif, .json, .md)" type:"path"`
+	ReportFormat string      `help:"Report file format" enum:"auto,junit,sarif,json,markdown,text" default:"auto"`
+	Raw          bool        `help:"Disable ANSI stripping, progress collapsing, path relativizing and NO_COLOR env"`
+	MaxOutput    string      `help:"Output budget across all results (e.g. 8000, 32kb, 5000tokens)"`
+	Truncate     string      `help:"Truncation strategy over budget" enum:"head-tail,head,tail,errors" default:"head-tail"`
+	CaptureLimit string      `help:"Output of each command stream kept in memory before spilling to disk" default:"4mb"`
+	Config       string      `help:"Load checks from a JSON config file" short:"c" type:"path"`
+	Check        []string    `help:"Add a check as KEY=VALUE pairs, repeatable (e.g. name=lint,cmd=make)" sep:"none"`
+	FilterError  string      `help:"How to handle failing filters" enum:"annotate,fallback,fail" default:"annotate"`
+	EnvFile      []string    `help:"Load environment variables from a .env file, repeatable" type:"path" sep:"none"`
+	CwdBase      string      `help:"Directory relative check cwd is based on" enum:"repo,payload" default:"repo"`
+	Stdin        string      `help:"Stdin of every command: payload for the hook payload, or a file path"`
+	Payload      bool        `help:"Read the hook payload from stdin (BLOCC_* variables in scripts, --record session)"`
+	OnSuccess    string      `help:"Output when all checks pass" enum:"none,summary,system-message" default:"none"`
+	Verbose      bool        `help:"List every command with its status and duration on stdout"`
+	Stream       bool        `help:"Print command output on stdout while it runs, prefixed with the check name"`
+	Record       bool        `help:"Record the run in the history file"`
+	NoCache      bool        `help:"Run every check even if its inputs are unchanged"`
+	Diff         bool        `help:"Report failures as new, remaining or fixed since the session's previous run"`
+	HistoryFile  string      `help:"History file (default: $XDG_STATE_HOME/blocc/history.jsonl)" type:"path"`
+
+	FailFast       bool  `help:"Stop after the first blocking failure" xor:"stop"`
+	StopOnCodes    []int `help:"Stop after a blocking check exits with one of these codes (default 2)" xor:"stop"`
+	ContinueAlways bool  `help:"Run every check regardless of failures" xor:"stop"`
+	BlockExitCode  int   `help:"Exit code when a check blocks" default:"2"`
+	ErrorExitCode  int   `help:"Exit code when blocc itself fails" default:"1"`
+
+	Run      RunCmd      `cmd:"" default:"withargs" help:"Execute commands (default)"`
+	Doctor   DoctorCmd   `cmd:"" help:"Validate filters and commands without running checks"`
+	History  HistoryCmd  `cmd:"" help:"Inspect recorded runs"`
+	Cache    CacheCmd    `cmd:"" help:"Manage cached results of checks with inputs"`
+	Watch    WatchCmd    `cmd:"" help:"Rerun checks when files change"`
+	Simulate SimulateCmd `cmd:"" help:"Run the configured hooks with a fabricated hook payload"`
 }
 
 func Parse() (*CLI, *kong.Context) {
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/cli"
+)
+
+func history(cliOptions *cli.CLI) (blocc.History, error) {
+	path := cliOptions.HistoryFile
+	if path == "" {
+		var err error
+		if path, err = blocc.DefaultHistoryPath(); err != nil {
+			return blocc.History{}, err
+		}
+	}
+	return blocc.History{Path: path}, nil
+}
+
+func recordRun(cliOptions *cli.CLI, entry blocc.HistoryEntry) error {
+	h, err := history(cliOptions)
+	if err != nil {
+		return err
+	}
+	return h.Append(entry)
+}
+
+func runHistory(cliOptions *cli.CLI, command string) int {
+	h, err := history(cliOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	entries, err := h.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case strings.HasPrefix(command, "history show"):
+		err = showRun(cliOptions, entries)
+	case strings.HasPrefix(command, "history stats"):
+		err = showStats(filterSession(entries, cliOptions.History.Stats.Session))
+	default:
+		err = listRuns(cliOptions, entries)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func filterSession(entries []blocc.HistoryEntry, session string) []blocc.HistoryEntry {
+	if session == "" {
+		return entries
+	}
+	var filtered []blocc.HistoryEntry
+	for _, e := range entries {
+		if e.SessionID == session {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func listRuns(cliOptions *cli.CLI, entries []blocc.HistoryEntry) error {
+	options := cliOptions.History.List
+	entries = filterSession(entries, options.Session)
+
+	if options.Blocked {
+		var blocked []blocc.HistoryEntry
+		for _, e := range entries {
+			if e.Blocked() {
+				blocked = append(blocked, e)
+			}
+		}
+		entries = blocked
+	}
+
+	if options.Limit > 0 && len(entries) > options.Limit {
+		entries = entries[len(entries)-options.Limit:]
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tRESULT\tSESSION\tSUMMARY")
+	for _, e := range entries {
+		result := "passed"
+		if e.Blocked() {
+			result = "blocked"
+		}
+		session := e.SessionID
+		if session == "" {
+			session = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			e.ID, e.StartedAt.Local().Format("2006-01-02 15:04:05"), result, session, e.Summary)
+	}
+	return w.Flush()
+}
+
+func showRun(cliOptions *cli.CLI, entries []blocc.HistoryEntry) error {
+	entry, err := blocc.FindHistoryEntry(entries, cliOptions.History.Show.ID)
+	if err != nil {
+		return err
+	}
+
+	reporter, err := blocc.NewReporter(cliOptions.Format)
+	if err != nil {
+		return err
+	}
+
+	output := blocc.NewErrorOutput(cliOptions.Message, entry.Results)
+	output.Summary = &entry.Summary
+	return reporter.Report(os.Stdout, output)
+}
+
+func showStats(entries []blocc.HistoryEntry) error {
+	stats := blocc.HistoryStats(entries)
+	if len(stats) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRUNS\tFAILURES\tFAILURE RATE\tFLAKY\tAVG DURATION")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.0f%%\t%d\t%s\n",
+			s.Check, s.Runs, s.Failures, s.FailureRate()*100, s.Flaky, blocc.Duration(s.AverageDuration()))
+	}
+	return w.Flush()
+}
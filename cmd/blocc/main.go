@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/shuntaka9576/blocc"
 	"github.com/shuntaka9576/blocc/cli"
@@ -13,7 +20,7 @@ func main() {
 
 	if cliOptions.Init {
 		err := blocc.InitSettings(
-			cliOptions.Commands,
+			cliOptions.Run.Commands,
 			cliOptions.Message,
 			cliOptions.Stdout,
 			cliOptions.StdoutFilter,
@@ -27,31 +34,500 @@ func main() {
 		ctx.Exit(0)
 	}
 
+	if strings.HasPrefix(ctx.Command(), "doctor") {
+		ctx.Exit(runDoctor(cliOptions))
+	}
+
+	if strings.HasPrefix(ctx.Command(), "cache") {
+		ctx.Exit(runCachePrune(cliOptions))
+	}
+
+	if strings.HasPrefix(ctx.Command(), "watch") {
+		ctx.Exit(runWatch(cliOptions))
+	}
+
+	if strings.HasPrefix(ctx.Command(), "simulate") {
+		ctx.Exit(runSimulate(cliOptions))
+	}
+
+	if strings.HasPrefix(ctx.Command(), "history") {
+		ctx.Exit(runHistory(cliOptions, ctx.Command()))
+	}
+
+	ctx.Exit(exitCode(cliOptions, runCommands(cliOptions)))
+}
+
+// exitCode maps the exit codes of runCommands, 2 for blocking and 1 for internal
+// errors, to --block-exit-code and --error-exit-code.
+func exitCode(cliOptions *cli.CLI, code int) int {
+	switch code {
+	case 2:
+		return cliOptions.BlockExitCode
+	case 1:
+		return cliOptions.ErrorExitCode
+	default:
+		return code
+	}
+}
+
+func runCommands(cliOptions *cli.CLI) int {
+	// Stdin is only read when something uses the payload, so that blocc run
+	// with piped input, e.g. in CI, does not wait for the input to end.
+	var payload *blocc.HookPayload
+	if payloadNeeded(cliOptions, cliOptions.Run.Commands) {
+		var err error
+		if payload, err = blocc.ReadStdinHookPayload(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	r, err := prepareRun(cliOptions, cliOptions.Run.Commands, payload)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return r.execute(context.Background())
+}
+
+// payloadNeeded reports whether an option or a check uses the hook payload.
+// Errors loading the checks are left for prepareRun to report.
+func payloadNeeded(cliOptions *cli.CLI, commands []string) bool {
+	if cliOptions.Payload || cliOptions.Diff || cliOptions.CwdBase == "payload" ||
+		cliOptions.Stdin == blocc.StdinPayload {
+		return true
+	}
+
+	global := blocc.CheckConfig{
+		StdoutFilter: cliOptions.StdoutFilter,
+		StderrFilter: cliOptions.StderrFilter,
+		StdoutPipe:   cliOptions.StdoutPipe,
+		StderrPipe:   cliOptions.StderrPipe,
+	}
+	configs, _ := checkConfigs(cliOptions, commands)
+	return slices.ContainsFunc(append(configs, global), blocc.CheckConfig.UsesPayload)
+}
+
+// run holds everything needed to execute the checks and report their results.
+type run struct {
+	cliOptions      *cli.CLI
+	payload         *blocc.HookPayload
+	executor        *blocc.Executor
+	checks          []blocc.Check
+	reporter        blocc.Reporter
+	fileReporter    blocc.Reporter
+	truncateOptions blocc.TruncateOptions
+}
+
+// prepareRun loads the checks from the config file, --check flags and commands.
+func prepareRun(cliOptions *cli.CLI, commands []string, payload *blocc.HookPayload) (*run, error) {
+	configs, err := checkConfigs(cliOptions, commands)
+	if err != nil {
+		return nil, err
+	}
+
 	// Default behavior: run commands
-	if len(cliOptions.Commands) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: no commands provided\n")
-		ctx.Exit(1)
+	if len(configs) == 0 {
+		return nil, errors.New("no commands provided")
+	}
+
+	r := &run{cliOptions: cliOptions, payload: payload}
+
+	if r.truncateOptions, err = parseTruncateOptions(cliOptions); err != nil {
+		return nil, err
+	}
+	if r.reporter, err = blocc.NewReporter(cliOptions.Format); err != nil {
+		return nil, err
+	}
+	if r.fileReporter, err = newFileReporter(cliOptions); err != nil {
+		return nil, err
+	}
+	if r.executor, err = newExecutor(cliOptions); err != nil {
+		return nil, err
 	}
 
-	executor := blocc.NewExecutor(cliOptions.Stdout, cliOptions.StdoutFilter, cliOptions.StderrFilter, cliOptions.NoStderr)
+	r.executor.WithHookPayload(payload)
+	if cliOptions.CwdBase == "payload" && payload != nil && payload.Cwd != "" {
+		r.executor.WithBaseDir(payload.Cwd)
+	}
 
-	var results []blocc.Result
-	var err error
+	if r.checks, err = blocc.BuildChecks(configs, r.executor.Defaults()); err != nil {
+		return nil, err
+	}
+
+	if r.checks, err = blocc.ExpandWorkspaces(r.checks, blocc.FindRepoRoot(r.executor.BaseDir())); err != nil {
+		return nil, err
+	}
+
+	if !cliOptions.NoCache {
+		useCache(r.executor, r.checks)
+	}
 
+	return r, nil
+}
+
+// execute runs the checks and reports the results as a hook would. It returns
+// 2 when a check blocks, 1 on internal errors and 0 otherwise.
+func (r *run) execute(ctx context.Context) int {
+	cliOptions, payload := r.cliOptions, r.payload
+	start := time.Now()
+
+	concurrency := 1
 	if cliOptions.Parallel {
-		results, err = executor.ExecuteParallel(cliOptions.Commands)
-	} else {
-		results, err = executor.ExecuteSequential(cliOptions.Commands)
+		concurrency = 0
 	}
+	if cliOptions.Jobs > 0 {
+		concurrency = cliOptions.Jobs
+	}
+	results, err := r.executor.ExecuteChecksContext(ctx, r.checks, concurrency)
 
-	if len(results) > 0 {
-		if outputErr := blocc.OutputError(cliOptions.Message, results); outputErr != nil {
-			ctx.Exit(1)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if ctx.Err() != nil {
+		// The run was superseded, e.g. by new changes in watch mode.
+		return 1
+	}
+
+	results = blocc.GroupByPackage(results)
+	summary := blocc.NewSummary(results, time.Since(start))
+	failed := blocc.FailedResults(results)
+	warnings := blocc.WarningResults(results)
+
+	if cliOptions.Record {
+		if err := recordRun(cliOptions, blocc.NewHistoryEntry(start, payload, results, summary)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if r.fileReporter != nil {
+		output := blocc.NewErrorOutput(cliOptions.Message, results)
+		output.Summary = &summary
+		if err := blocc.WriteReportFile(cliOptions.ReportFile, r.fileReporter, output); err != nil {
+			// Keep going so that failures still reach Claude.
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	var diff *blocc.SessionDiff
+	if cliOptions.Diff && payload != nil && payload.SessionID != "" {
+		var previous []blocc.Result
+		if previous, err = diffSession(payload.SessionID, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else {
+			d := blocc.DiffResults(previous, results)
+			diff = &d
+			failed = blocc.OmitUnchanged(previous, failed)
+		}
+	}
+
+	if len(failed) > 0 {
+		if cliOptions.Verbose {
+			_ = blocc.WriteStatusList(os.Stdout, results)
+		}
+
+		// Warnings ride along with blocking failures so Claude sees them in one report.
+		reported := append(failed, warnings...)
+		output := blocc.NewErrorOutput(cliOptions.Message, blocc.TruncateResults(reported, r.truncateOptions))
+		output.Summary = &summary
+		if diff != nil {
+			output.Diff = diff
+			if cliOptions.Message == "" {
+				output.Message += " (" + diff.String() + ")"
+			}
+		}
+		if outputErr := blocc.WriteError(os.Stderr, r.reporter, output); outputErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", outputErr)
+			return 1
+		}
+		return 2
+	}
+
+	if len(warnings) > 0 {
+		output := blocc.NewErrorOutput("", blocc.TruncateResults(warnings, r.truncateOptions))
+		output.Message = fmt.Sprintf("%d warning(s), not blocking", len(warnings))
+		if err := writeWarnings(cliOptions, results, output, summary); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if err := writeSuccess(cliOptions, results, summary); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// diffSession returns the results of the session's previous run and stores results as its last run.
+func diffSession(sessionID string, results []blocc.Result) ([]blocc.Result, error) {
+	store, err := blocc.DefaultSessionStore()
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := store.Load(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return previous, store.Save(sessionID, results)
+}
+
+// writeSuccess reports a passing run on stdout according to --on-success and --verbose.
+func writeSuccess(cliOptions *cli.CLI, results []blocc.Result, summary blocc.Summary) error {
+	var b strings.Builder
+	if cliOptions.Verbose {
+		if err := blocc.WriteStatusList(&b, results); err != nil {
+			return err
+		}
+	}
+
+	switch cliOptions.OnSuccess {
+	case "summary":
+		b.WriteString("blocc: " + summary.String() + "\n")
+	case "system-message":
+		// Claude Code shows systemMessage to the user; it must be the only stdout output.
+		message := "blocc: " + summary.String()
+		if b.Len() > 0 {
+			message += "\n" + strings.TrimSuffix(b.String(), "\n")
 		}
-		ctx.Exit(2)
+		return json.NewEncoder(os.Stdout).Encode(map[string]string{"systemMessage": message})
 	}
 
+	_, err := fmt.Fprint(os.Stdout, b.String())
+	return err
+}
+
+// writeWarnings reports failed checks with warn severity without blocking. Claude Code
+// shows a systemMessage to the user, so that is the default; --on-success=summary
+// writes plain text instead.
+func writeWarnings(cliOptions *cli.CLI, results []blocc.Result, output blocc.ErrorOutput, summary blocc.Summary) error {
+	var b strings.Builder
+	if cliOptions.Verbose {
+		if err := blocc.WriteStatusList(&b, results); err != nil {
+			return err
+		}
+	}
+	if err := (blocc.TextReporter{}).Report(&b, output); err != nil {
+		return err
+	}
+	b.WriteString("blocc: " + summary.String() + "\n")
+
+	if cliOptions.OnSuccess == "summary" {
+		_, err := fmt.Fprint(os.Stdout, b.String())
+		return err
+	}
+	message := strings.TrimSuffix(b.String(), "\n")
+	return json.NewEncoder(os.Stdout).Encode(map[string]string{"systemMessage": message})
+}
+
+// newFileReporter returns the reporter for --report-file, or nil when no report file is requested.
+func newFileReporter(cliOptions *cli.CLI) (blocc.Reporter, error) {
+	if cliOptions.ReportFile == "" {
+		return nil, nil
+	}
+
+	format := cliOptions.ReportFormat
+	if format == "" || format == "auto" {
+		var err error
+		if format, err = blocc.ReportFormatForPath(cliOptions.ReportFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return blocc.NewReporter(format)
+}
+
+// checkConfigs collects checks from the config file, --check flags and positional commands, in that order.
+func checkConfigs(cliOptions *cli.CLI, commands []string) ([]blocc.CheckConfig, error) {
+	var configs []blocc.CheckConfig
+
+	if cliOptions.Config != "" {
+		config, err := blocc.LoadConfig(cliOptions.Config)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config.Checks...)
+	}
+
+	for _, s := range cliOptions.Check {
+		config, err := blocc.ParseCheckFlag(s)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	for _, cmdStr := range commands {
+		configs = append(configs, blocc.CheckConfig{Command: cmdStr})
+	}
+
+	return configs, nil
+}
+
+func newExecutor(cliOptions *cli.CLI) (*blocc.Executor, error) {
+	stdoutPipeline, err := blocc.NewPipeline(cliOptions.StdoutFilter, cliOptions.StdoutPipe)
+	if err != nil {
+		return nil, err
+	}
+
+	stderrPipeline, err := blocc.NewPipeline(cliOptions.StderrFilter, cliOptions.StderrPipe)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := blocc.ParseFilterErrorPolicy(cliOptions.FilterError)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := blocc.NewExecutorWithPipelines(cliOptions.Stdout, stdoutPipeline, stderrPipeline, cliOptions.NoStderr)
+	executor.WithFilterErrorPolicy(policy).WithRaw(cliOptions.Raw).WithStopPolicy(stopPolicy(cliOptions))
+	executor.WithStdin(cliOptions.Stdin)
+	if cliOptions.Stream {
+		executor.WithStream(os.Stdout)
+	}
+
+	captureLimit, err := blocc.ParseOutputBudget(cliOptions.CaptureLimit)
 	if err != nil {
-		ctx.Exit(1)
+		return nil, fmt.Errorf("invalid capture limit: %w", err)
 	}
+	executor.WithCaptureLimit(int64(captureLimit))
+
+	if len(cliOptions.EnvFile) > 0 {
+		env := make(map[string]string)
+		for _, path := range cliOptions.EnvFile {
+			fileEnv, err := blocc.LoadEnvFile(path)
+			if err != nil {
+				return nil, err
+			}
+			maps.Copy(env, fileEnv)
+		}
+		executor.WithEnv(env)
+	}
+
+	return executor, nil
+}
+
+// useCache attaches the result cache to the executor when a check declares
+// inputs. Without a cache directory the checks simply run uncached.
+func useCache(executor *blocc.Executor, checks []blocc.Check) {
+	if !slices.ContainsFunc(checks, func(c blocc.Check) bool { return len(c.Inputs) > 0 }) {
+		return
+	}
+	cache, err := blocc.DefaultCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: running without a cache: %v\n", err)
+		return
+	}
+	// Keep the cache from growing without bounds.
+	_, _ = cache.Prune(blocc.DefaultCacheMaxAge)
+	executor.WithCache(cache)
+}
+
+// stopPolicy returns the policy selected with --fail-fast, --stop-on-codes or --continue-always.
+func stopPolicy(cliOptions *cli.CLI) blocc.StopPolicy {
+	switch {
+	case cliOptions.ContinueAlways:
+		return blocc.StopPolicy{}
+	case cliOptions.FailFast:
+		return blocc.StopPolicy{FailFast: true}
+	case len(cliOptions.StopOnCodes) > 0:
+		return blocc.StopPolicy{Codes: cliOptions.StopOnCodes}
+	default:
+		return blocc.DefaultStopPolicy
+	}
+}
+
+func runCachePrune(cliOptions *cli.CLI) int {
+	cache, err := blocc.DefaultCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	removed, err := cache.Prune(cliOptions.Cache.Prune.MaxAge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Removed %d cached result(s)\n", removed)
+	return 0
+}
+
+func runDoctor(cliOptions *cli.CLI) int {
+	var diagnoses []blocc.Diagnosis
+	diagnoses = append(diagnoses, blocc.DiagnoseFilters("stdout", cliOptions.StdoutFilter, cliOptions.StdoutPipe)...)
+	diagnoses = append(diagnoses, blocc.DiagnoseFilters("stderr", cliOptions.StderrFilter, cliOptions.StderrPipe)...)
+
+	configs, err := checkConfigs(cliOptions, cliOptions.Doctor.Commands)
+	if err != nil {
+		diagnoses = append(diagnoses, blocc.Diagnosis{Subject: "checks", Err: err})
+	}
+	for _, config := range configs {
+		diagnoses = append(diagnoses, blocc.DiagnoseCheckConfig(config)...)
+	}
+	if err := validateNeeds(configs); err != nil {
+		diagnoses = append(diagnoses, blocc.Diagnosis{Subject: "check dependencies", Err: err})
+	}
+
+	if len(diagnoses) == 0 {
+		fmt.Println("Nothing to check")
+		return 0
+	}
+
+	problems := 0
+	for _, d := range diagnoses {
+		if d.Err != nil {
+			problems++
+			fmt.Printf("error  %s: %v\n", d.Subject, d.Err)
+		} else {
+			fmt.Printf("ok     %s\n", d.Subject)
+		}
+	}
+
+	if problems > 0 {
+		fmt.Printf("%d problem(s) found\n", problems)
+		return 1
+	}
+
+	return 0
+}
+
+// validateNeeds checks the needs of configs without building their filters.
+func validateNeeds(configs []blocc.CheckConfig) error {
+	checks := make([]blocc.Check, len(configs))
+	for i, config := range configs {
+		checks[i] = blocc.Check{Name: config.Name, Needs: config.Needs}
+		if checks[i].Name == "" {
+			checks[i].Name = config.Command
+		}
+	}
+	return blocc.ValidateNeeds(checks)
+}
+
+func parseTruncateOptions(cliOptions *cli.CLI) (blocc.TruncateOptions, error) {
+	if cliOptions.MaxOutput == "" {
+		return blocc.TruncateOptions{}, nil
+	}
+
+	maxBytes, err := blocc.ParseOutputBudget(cliOptions.MaxOutput)
+	if err != nil {
+		return blocc.TruncateOptions{}, err
+	}
+
+	strategy, err := blocc.ParseTruncateStrategy(cliOptions.Truncate)
+	if err != nil {
+		return blocc.TruncateOptions{}, err
+	}
+
+	return blocc.TruncateOptions{
+		MaxBytes: maxBytes,
+		Strategy: strategy,
+	}, nil
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/cli"
+)
+
+// runSimulate pipes a fabricated hook payload into the hooks configured for
+// the event, as Claude Code would, and explains how Claude Code would react.
+func runSimulate(cliOptions *cli.CLI) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := simulate(ctx, os.Stdout, cliOptions.Simulate); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func simulate(ctx context.Context, w io.Writer, opts cli.SimulateCmd) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	projectDir := blocc.FindRepoRoot(cwd)
+
+	toolName := ""
+	if opts.Event == blocc.EventPreToolUse || opts.Event == blocc.EventPostToolUse {
+		toolName = opts.Tool
+	}
+	simulation := blocc.SimulationOptions{
+		SessionID:      opts.Session,
+		Cwd:            projectDir,
+		StopHookActive: opts.StopHookActive,
+		ToolName:       toolName,
+		FilePath:       opts.File,
+		Prompt:         opts.Prompt,
+	}
+	payload, err := blocc.SimulatePayload(opts.Event, simulation)
+	if err != nil {
+		return err
+	}
+
+	var hooks []blocc.ConfiguredHook
+	if opts.Command != "" {
+		hooks = []blocc.ConfiguredHook{{Source: "--command", Command: opts.Command, Timeout: blocc.DefaultHookTimeout}}
+	} else {
+		files := blocc.SettingsFiles(projectDir)
+		if hooks, err = blocc.FindHooks(files, opts.Event, toolName); err != nil {
+			return err
+		}
+		if len(hooks) == 0 {
+			return fmt.Errorf("no %s hook matches in %s (use --command to try one)", opts.Event, strings.Join(files, ", "))
+		}
+	}
+
+	fmt.Fprintf(w, "Payload (%s):\n%s\n", opts.Event, payload)
+	for _, hook := range hooks {
+		fmt.Fprintf(w, "\nHook: %s\n", hook.Command)
+		fmt.Fprintf(w, "From: %s\n", hook.Source)
+
+		run, err := blocc.RunHook(ctx, hook, projectDir, payload)
+		if err != nil {
+			return err
+		}
+		if run.TimedOut {
+			fmt.Fprintf(w, "Exit code: timed out after %s\n", hook.Timeout)
+		} else {
+			fmt.Fprintf(w, "Exit code: %d (%s)\n", run.ExitCode, run.Duration.Round(time.Millisecond))
+		}
+		printHookStream(w, "Stdout", run.Stdout)
+		printHookStream(w, "Stderr", run.Stderr)
+
+		fmt.Fprintln(w, "Claude Code:")
+		for _, line := range blocc.InterpretHook(opts.Event, simulation, run) {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+	return nil
+}
+
+func printHookStream(w io.Writer, name, output string) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		fmt.Fprintf(w, "%s: (empty)\n", name)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", name)
+	for _, line := range strings.Split(output, "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
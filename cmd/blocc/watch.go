@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/cli"
+)
+
+// runWatch reruns the checks whenever files in the repository change, until
+// interrupted. A run still in flight when new changes arrive is cancelled.
+func runWatch(cliOptions *cli.CLI) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	watcher := blocc.NewWatcher(blocc.FindRepoRoot(cwd))
+	watcher.Interval = cliOptions.Watch.Interval
+	watcher.Debounce = cliOptions.Watch.Debounce
+	if cliOptions.ReportFile != "" {
+		// Writing the report must not trigger another run.
+		if reportFile, err := filepath.Abs(cliOptions.ReportFile); err == nil {
+			watcher.Exclude = append(watcher.Exclude, reportFile)
+		}
+	}
+
+	changes := make(chan []string)
+	watchErr := make(chan error, 1)
+	go func() { watchErr <- watcher.Watch(ctx, changes) }()
+
+	fmt.Fprintf(os.Stderr, "blocc watch: watching %s, press Ctrl+C to stop\n", watcher.Root)
+
+	cancelRun := context.CancelFunc(func() {})
+	done := make(chan struct{})
+	close(done)
+
+	start := func(changed []string) {
+		cancelRun()
+		<-done
+
+		var runCtx context.Context
+		runCtx, cancelRun = context.WithCancel(ctx)
+		done = make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			watchRun(runCtx, cliOptions, changed)
+		}(done)
+	}
+
+	start(nil)
+	for {
+		select {
+		case changed := <-changes:
+			start(changed)
+		case err := <-watchErr:
+			cancelRun()
+			<-done
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+}
+
+// watchRun runs the checks once, printing the output Claude would receive
+// followed by how the hook's exit code would be interpreted.
+func watchRun(ctx context.Context, cliOptions *cli.CLI, changed []string) {
+	fmt.Fprintf(os.Stderr, "\nblocc watch: [%s] %s\n", time.Now().Format("15:04:05"), describeChanges(changed))
+
+	// Reload the config every time so that filters and budgets can be tuned while watching.
+	r, err := prepareRun(cliOptions, cliOptions.Watch.Commands, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+
+	code := exitCode(cliOptions, r.execute(ctx))
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "blocc watch: run cancelled")
+		return
+	}
+
+	switch code {
+	case 0:
+		fmt.Fprintln(os.Stderr, "blocc watch: exit code 0, Claude continues")
+	case 2:
+		fmt.Fprintln(os.Stderr, "blocc watch: exit code 2, Claude is blocked and receives stderr")
+	default:
+		fmt.Fprintf(os.Stderr, "blocc watch: exit code %d, shown to the user without blocking Claude\n", code)
+	}
+}
+
+func describeChanges(changed []string) string {
+	switch {
+	case len(changed) == 0:
+		return "running checks"
+	case len(changed) <= 3:
+		return "changed: " + strings.Join(changed, ", ")
+	default:
+		return fmt.Sprintf("changed: %s and %d more", strings.Join(changed[:3], ", "), len(changed)-3)
+	}
+}
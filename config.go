@@ -0,0 +1,381 @@
+package blocc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the content of a blocc configuration file.
+type Config struct {
+	Checks []CheckConfig `json:"checks"`
+}
+
+// CheckConfig describes a check in a configuration file or a --check flag.
+// Unset fields fall back to the global flags.
+type CheckConfig struct {
+	Name         string            `json:"name,omitempty"`
+	Command      string            `json:"command"`
+	Stdout       *bool             `json:"stdout,omitempty"`
+	NoStderr     *bool             `json:"noStderr,omitempty"`
+	StdoutFilter string            `json:"stdoutFilter,omitempty"`
+	StderrFilter string            `json:"stderrFilter,omitempty"`
+	StdoutPipe   []string          `json:"stdoutPipe,omitempty"`
+	StderrPipe   []string          `json:"stderrPipe,omitempty"`
+	Raw          *bool             `json:"raw,omitempty"`
+	Timeout      string            `json:"timeout,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	Cwd          string            `json:"cwd,omitempty"`
+	Stdin        string            `json:"stdin,omitempty"`
+	Severity     string            `json:"severity,omitempty"`
+	AllowFailure bool              `json:"allowFailure,omitempty"`
+	SuccessCodes []int             `json:"successCodes,omitempty"`
+	WarnCodes    []int             `json:"warnCodes,omitempty"`
+	Inputs       []string          `json:"inputs,omitempty"`
+	Needs        []string          `json:"needs,omitempty"`
+	Foreach      string            `json:"foreach,omitempty"`
+	ChangedOnly  bool              `json:"changedOnly,omitempty"`
+	Retries      int               `json:"retries,omitempty"`
+	RetryDelay   string            `json:"retryDelay,omitempty"`
+	RetryBackoff float64           `json:"retryBackoff,omitempty"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+
+	var config Config
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// ParseCheckFlag parses a --check value such as
+// "name=test,command='go test ./...',stdout,timeout=5m".
+// Values containing commas can be wrapped in single or double quotes.
+func ParseCheckFlag(s string) (CheckConfig, error) {
+	var config CheckConfig
+
+	fields, err := splitCheckFlag(s)
+	if err != nil {
+		return config, err
+	}
+
+	for _, field := range fields {
+		key, value, hasValue := strings.Cut(field, "=")
+		if err := config.set(strings.TrimSpace(key), value, hasValue); err != nil {
+			return config, fmt.Errorf("invalid check %q: %w", s, err)
+		}
+	}
+
+	if config.Command == "" {
+		return config, fmt.Errorf("invalid check %q: command is required", s)
+	}
+
+	return config, nil
+}
+
+func (c *CheckConfig) set(key, value string, hasValue bool) error {
+	switch key {
+	case "stdout", "no-stderr", "raw", "allow-failure", "changed-only":
+		enabled := true
+		if hasValue {
+			var err error
+			if enabled, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		switch key {
+		case "stdout":
+			c.Stdout = &enabled
+		case "no-stderr":
+			c.NoStderr = &enabled
+		case "raw":
+			c.Raw = &enabled
+		case "changed-only":
+			c.ChangedOnly = enabled
+		default:
+			c.AllowFailure = enabled
+		}
+		return nil
+	}
+
+	if !hasValue {
+		return fmt.Errorf("%s requires a value", key)
+	}
+
+	switch key {
+	case "name":
+		c.Name = value
+	case "command", "cmd":
+		c.Command = value
+	case "stdout-filter":
+		c.StdoutFilter = value
+	case "stderr-filter":
+		c.StderrFilter = value
+	case "stdout-pipe":
+		c.StdoutPipe = append(c.StdoutPipe, value)
+	case "stderr-pipe":
+		c.StderrPipe = append(c.StderrPipe, value)
+	case "timeout":
+		c.Timeout = value
+	case "cwd":
+		c.Cwd = value
+	case "stdin":
+		c.Stdin = value
+	case "severity":
+		c.Severity = value
+	case "input":
+		c.Inputs = append(c.Inputs, value)
+	case "needs":
+		c.Needs = append(c.Needs, value)
+	case "foreach":
+		c.Foreach = value
+	case "retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("retries must be a non-negative integer, got %q", value)
+		}
+		c.Retries = n
+	case "success-code", "warn-code":
+		code, err := parseExitCode(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if key == "success-code" {
+			c.SuccessCodes = append(c.SuccessCodes, code)
+		} else {
+			c.WarnCodes = append(c.WarnCodes, code)
+		}
+	case "retry-delay":
+		c.RetryDelay = value
+	case "retry-backoff":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("retry-backoff must be a number, got %q", value)
+		}
+		c.RetryBackoff = f
+
+	case "env":
+		k, v, ok := strings.Cut(value, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("env must be KEY=VALUE, got %q", value)
+		}
+		if c.Env == nil {
+			c.Env = make(map[string]string)
+		}
+		c.Env[k] = v
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+
+	return nil
+}
+
+// parseExitCode parses an exit code between 0 and 255.
+func parseExitCode(s string) (int, error) {
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 0 || code > 255 {
+		return 0, fmt.Errorf("exit code must be between 0 and 255, got %q", s)
+	}
+	return code, nil
+}
+
+// splitCheckFlag splits s on commas outside of quotes and removes the quotes.
+func splitCheckFlag(s string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ',':
+			fields = append(fields, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("invalid check %q: unterminated quote", s)
+	}
+
+	fields = append(fields, current.String())
+	return fields, nil
+}
+
+// UsesPayload reports whether the check reads the hook payload, through
+// stdin=payload or a BLOCC_* variable in its command, cwd, env or filters.
+func (c CheckConfig) UsesPayload() bool {
+	if c.Stdin == StdinPayload {
+		return true
+	}
+	fields := []string{c.Command, c.Cwd, c.StdoutFilter, c.StderrFilter}
+	fields = append(fields, c.StdoutPipe...)
+	fields = append(fields, c.StderrPipe...)
+	fields = append(fields, slices.Collect(maps.Values(c.Env))...)
+	return slices.ContainsFunc(fields, func(s string) bool { return strings.Contains(s, "BLOCC_") })
+}
+
+// Check builds the runtime check, using defaults for every setting the config leaves unset.
+func (c CheckConfig) Check(defaults Check) (Check, error) {
+	check := defaults
+	check.Name = c.Name
+	if check.Name == "" {
+		check.Name = c.Command
+	}
+	check.Command = c.Command
+	check.Dir = ExpandEnv(c.Cwd, defaults.Env)
+	if c.Stdin != "" {
+		check.Stdin = c.Stdin
+	}
+	check.Inputs = c.Inputs
+	check.Needs = c.Needs
+
+	if c.Stdout != nil {
+		check.IncludeStdout = *c.Stdout
+	}
+	if c.NoStderr != nil {
+		check.NoStderr = *c.NoStderr
+	}
+	if c.Raw != nil {
+		check.Raw = *c.Raw
+	}
+
+	var err error
+	if c.StdoutFilter != "" || len(c.StdoutPipe) > 0 {
+		if check.StdoutPipeline, err = NewPipeline(c.StdoutFilter, c.StdoutPipe); err != nil {
+			return check, fmt.Errorf("check %q: %w", check.Name, err)
+		}
+	}
+	if c.StderrFilter != "" || len(c.StderrPipe) > 0 {
+		if check.StderrPipeline, err = NewPipeline(c.StderrFilter, c.StderrPipe); err != nil {
+			return check, fmt.Errorf("check %q: %w", check.Name, err)
+		}
+	}
+
+	if c.Timeout != "" {
+		if check.Timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return check, fmt.Errorf("check %q: invalid timeout: %w", check.Name, err)
+		}
+	}
+
+	if c.Foreach != "" {
+		if check.WorkspaceKind, err = ParseForeach(c.Foreach); err != nil {
+			return check, fmt.Errorf("check %q: %w", check.Name, err)
+		}
+		check.Foreach = true
+	}
+	if c.ChangedOnly && c.Foreach == "" {
+		return check, fmt.Errorf("check %q: changedOnly requires foreach", check.Name)
+	}
+	check.ChangedOnly = c.ChangedOnly
+
+	if check.Severity, err = ParseSeverity(c.Severity); err != nil {
+		return check, fmt.Errorf("check %q: %w", check.Name, err)
+	}
+	if c.AllowFailure {
+		if c.Severity != "" && check.Severity != SeverityIgnore {
+			return check, fmt.Errorf("check %q: allowFailure conflicts with severity %q", check.Name, c.Severity)
+		}
+		check.Severity = SeverityIgnore
+	}
+
+	for _, code := range slices.Concat(c.SuccessCodes, c.WarnCodes) {
+		if code < 0 || code > 255 {
+			return check, fmt.Errorf("check %q: exit code must be between 0 and 255, got %d", check.Name, code)
+		}
+	}
+	for _, code := range c.WarnCodes {
+		if slices.Contains(c.SuccessCodes, code) {
+			return check, fmt.Errorf("check %q: exit code %d is both a success and a warning code", check.Name, code)
+		}
+	}
+	check.SuccessCodes = c.SuccessCodes
+	check.WarnCodes = c.WarnCodes
+
+	if c.Retries < 0 {
+		return check, fmt.Errorf("check %q: retries must not be negative", check.Name)
+	}
+	check.Retries = c.Retries
+	if c.RetryDelay != "" {
+		if check.RetryDelay, err = time.ParseDuration(c.RetryDelay); err != nil {
+			return check, fmt.Errorf("check %q: invalid retry delay: %w", check.Name, err)
+		}
+	}
+	if c.RetryBackoff < 0 {
+		return check, fmt.Errorf("check %q: retry backoff must not be negative", check.Name)
+	}
+	check.RetryBackoff = c.RetryBackoff
+
+	for _, pattern := range c.Inputs {
+		if err := ValidateGlob(pattern); err != nil {
+			return check, fmt.Errorf("check %q: %w", check.Name, err)
+		}
+	}
+
+	if len(c.Env) > 0 {
+		check.Env = make(map[string]string, len(c.Env)+len(defaults.Env))
+		maps.Copy(check.Env, defaults.Env)
+		// Values expand from the defaults and the process environment, not from
+		// each other, so that the result does not depend on map order.
+		for k, v := range c.Env {
+			check.Env[k] = ExpandEnv(v, defaults.Env)
+		}
+	}
+
+	return check, nil
+}
+
+// BuildChecks converts check configs into runtime checks and rejects duplicate
+// names, unknown needs and dependency cycles.
+func BuildChecks(configs []CheckConfig, defaults Check) ([]Check, error) {
+	checks := make([]Check, 0, len(configs))
+	seen := make(map[string]bool, len(configs))
+
+	for _, config := range configs {
+		if config.Command == "" {
+			return nil, fmt.Errorf("check %q: command is required", config.Name)
+		}
+
+		check, err := config.Check(defaults)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.Name != "" {
+			if seen[check.Name] {
+				return nil, fmt.Errorf("duplicate check name %q", check.Name)
+			}
+			seen[check.Name] = true
+		}
+
+		checks = append(checks, check)
+	}
+
+	if err := ValidateNeeds(checks); err != nil {
+		return nil, err
+	}
+
+	return checks, nil
+}
@@ -0,0 +1,268 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCheckFlag(t *testing.T) {
+	enabled := true
+	disabled := false
+
+	tests := []struct {
+		name    string
+		input   string
+		want    CheckConfig
+		wantErr string
+	}{
+		{
+			name:  "command only",
+			input: "command=go test ./...",
+			want:  CheckConfig{Command: "go test ./..."},
+		},
+		{
+			name: "all keys",
+			input: "name=test,cmd=go test ./...,stdout,no-stderr=false,timeout=5m,cwd=api,env=A=1,env=B=2,allow-failure," +
+				"input=**/*.go,input=go.mod,needs=build,retries=2,retry-delay=1s,retry-backoff=2," +
+				"severity=ignore,success-code=1,warn-code=3,foreach=workspace:go,changed-only,stdin=payload",
+			want: CheckConfig{
+				Name:         "test",
+				Command:      "go test ./...",
+				Stdout:       &enabled,
+				NoStderr:     &disabled,
+				Timeout:      "5m",
+				Cwd:          "api",
+				Stdin:        "payload",
+				Env:          map[string]string{"A": "1", "B": "2"},
+				Severity:     "ignore",
+				AllowFailure: true,
+				SuccessCodes: []int{1},
+				WarnCodes:    []int{3},
+				Inputs:       []string{"**/*.go", "go.mod"},
+				Needs:        []string{"build"},
+				Foreach:      "workspace:go",
+				ChangedOnly:  true,
+				Retries:      2,
+				RetryDelay:   "1s",
+				RetryBackoff: 2,
+			},
+		},
+		{
+			name: "quoted values keep commas",
+			input: `name=spell,cmd=cspell lint .,stdout-pipe='extract:Unknown word \((\w+)\)',` +
+				`stdout-pipe=sort,stderr-filter="cut -d, -f1"`,
+			want: CheckConfig{
+				Name:         "spell",
+				Command:      "cspell lint .",
+				StdoutPipe:   []string{`extract:Unknown word \((\w+)\)`, "sort"},
+				StderrFilter: "cut -d, -f1",
+			},
+		},
+		{name: "missing command", input: "name=test", wantErr: "command is required"},
+		{name: "unknown key", input: "cmd=make,priority=3", wantErr: `unknown key "priority"`},
+		{name: "invalid exit code", input: "cmd=grep x,success-code=256", wantErr: "between 0 and 255"},
+		{name: "invalid retries", input: "cmd=make,retries=-1", wantErr: "non-negative integer"},
+		{name: "missing value", input: "cmd=make,cwd", wantErr: "cwd requires a value"},
+		{name: "invalid bool", input: "cmd=make,stdout=maybe", wantErr: "stdout"},
+		{name: "invalid env", input: "cmd=make,env=NOVALUE", wantErr: "KEY=VALUE"},
+		{name: "unterminated quote", input: "cmd='make", wantErr: "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCheckFlag(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseCheckFlag() error = %v, want contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCheckFlag() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCheckFlag() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	validPath := filepath.Join(dir, "blocc.json")
+	valid := `{
+  "checks": [
+    {"name": "lint", "command": "make lint", "stdoutPipe": ["head:20"], "timeout": "1m"},
+    {"command": "make test", "env": {"CI": "1"}, "cwd": "api", "allowFailure": true}
+  ]
+}`
+	if err := os.WriteFile(validPath, []byte(valid), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(validPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if len(config.Checks) != 2 {
+		t.Fatalf("LoadConfig() checks = %d, want 2", len(config.Checks))
+	}
+
+	if config.Checks[0].Name != "lint" || config.Checks[1].Env["CI"] != "1" || !config.Checks[1].AllowFailure {
+		t.Errorf("LoadConfig() = %+v", config.Checks)
+	}
+
+	unknownPath := filepath.Join(dir, "unknown.json")
+	if err := os.WriteFile(unknownPath, []byte(`{"checks": [{"command": "make", "stdot": true}]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(unknownPath); err == nil || !strings.Contains(err.Error(), "stdot") {
+		t.Errorf("LoadConfig() error = %v, want unknown field error", err)
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadConfig() expected error for missing file")
+	}
+}
+
+func TestBuildChecks(t *testing.T) {
+	defaultPipeline, err := NewPipeline("", []string{"head:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := Check{
+		IncludeStdout:  true,
+		StdoutPipeline: defaultPipeline,
+		Env:            map[string]string{"GLOBAL": "1"},
+	}
+	disabled := false
+
+	checks, err := BuildChecks([]CheckConfig{
+		{Command: "make lint"},
+		{
+			Name:       "test",
+			Command:    "go test ./...",
+			Stdout:     &disabled,
+			StdoutPipe: []string{"grep:FAIL", "tail:5"},
+			Timeout:    "90s",
+			Env:        map[string]string{"CGO_ENABLED": "0", "OUT": "${GLOBAL}/out"},
+			Cwd:        "api$GLOBAL",
+			Severity:   "warn",
+		},
+		{Command: "cspell .", AllowFailure: true},
+	}, defaults)
+	if err != nil {
+		t.Fatalf("BuildChecks() error = %v", err)
+	}
+
+	lint, test, spell := checks[0], checks[1], checks[2]
+
+	if lint.Name != "make lint" || !lint.IncludeStdout || len(lint.StdoutPipeline) != 1 {
+		t.Errorf("lint check did not inherit defaults: %+v", lint)
+	}
+
+	if test.IncludeStdout || len(test.StdoutPipeline) != 2 || test.Timeout != 90*time.Second || test.Dir != "api1" {
+		t.Errorf("test check overrides not applied: %+v", test)
+	}
+
+	if lint.Severity != SeverityBlock || test.Severity != SeverityWarn || spell.Severity != SeverityIgnore {
+		t.Errorf("severities = %q, %q, %q", lint.Severity, test.Severity, spell.Severity)
+	}
+
+	if test.Env["GLOBAL"] != "1" || test.Env["CGO_ENABLED"] != "0" || test.Env["OUT"] != "1/out" {
+		t.Errorf("test check env = %v", test.Env)
+	}
+
+	errorTests := []struct {
+		name    string
+		configs []CheckConfig
+		wantErr string
+	}{
+		{
+			name:    "duplicate names",
+			configs: []CheckConfig{{Name: "a", Command: "true"}, {Name: "a", Command: "false"}},
+			wantErr: "duplicate check name",
+		},
+		{
+			name:    "invalid filter",
+			configs: []CheckConfig{{Command: "true", StderrPipe: []string{"nope"}}},
+			wantErr: "unknown filter",
+		},
+		{
+			name:    "invalid timeout",
+			configs: []CheckConfig{{Command: "true", Timeout: "soon"}},
+			wantErr: "invalid timeout",
+		},
+		{
+			name:    "missing command",
+			configs: []CheckConfig{{Name: "empty"}},
+			wantErr: "command is required",
+		},
+		{
+			name:    "unknown severity",
+			configs: []CheckConfig{{Command: "true", Severity: "fatal"}},
+			wantErr: "unknown severity",
+		},
+		{
+			name:    "allowFailure with severity",
+			configs: []CheckConfig{{Command: "true", Severity: "warn", AllowFailure: true}},
+			wantErr: "conflicts with severity",
+		},
+		{
+			name:    "unknown foreach",
+			configs: []CheckConfig{{Command: "true", Foreach: "module"}},
+			wantErr: "unknown foreach",
+		},
+		{
+			name:    "changedOnly without foreach",
+			configs: []CheckConfig{{Command: "true", ChangedOnly: true}},
+			wantErr: "changedOnly requires foreach",
+		},
+		{
+			name:    "success and warning code",
+			configs: []CheckConfig{{Command: "diff a b", SuccessCodes: []int{1}, WarnCodes: []int{1}}},
+			wantErr: "both a success and a warning code",
+		},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildChecks(tt.configs, Check{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildChecks() error = %v, want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckConfigUsesPayload(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CheckConfig
+		want   bool
+	}{
+		{name: "plain", config: CheckConfig{Command: "go test ./...", Env: map[string]string{"MODE": "test"}}},
+		{name: "stdin payload", config: CheckConfig{Command: "./lint.sh", Stdin: StdinPayload}, want: true},
+		{name: "stdin file", config: CheckConfig{Command: "./lint.sh", Stdin: "files.txt"}},
+		{name: "command", config: CheckConfig{Command: "golangci-lint run $BLOCC_FILE_PATH"}, want: true},
+		{name: "env", config: CheckConfig{Command: "make", Env: map[string]string{"FILE": "${BLOCC_FILE_PATH}"}}, want: true},
+		{name: "cwd", config: CheckConfig{Command: "make", Cwd: "$BLOCC_CWD"}, want: true},
+		{name: "filter", config: CheckConfig{Command: "make", StdoutPipe: []string{"sh:grep $BLOCC_FILE_PATH"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.UsesPayload(); got != tt.want {
+				t.Errorf("UsesPayload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
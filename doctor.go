@@ -0,0 +1,154 @@
+package blocc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// doctorSample is fed to shell filters to check they run at all.
+const doctorSample = "blocc doctor sample line\n"
+
+// Diagnosis is the outcome of validating one filter stage or command.
+type Diagnosis struct {
+	Subject string
+	Err     error
+}
+
+// DiagnoseFilters validates a stream's shell filter and built-in stage specs
+// without running any check command.
+func DiagnoseFilters(stream, shellFilter string, specs []string) []Diagnosis {
+	var diagnoses []Diagnosis
+
+	if shellFilter != "" {
+		diagnoses = append(diagnoses, Diagnosis{
+			Subject: fmt.Sprintf("%s filter %q", stream, shellFilter),
+			Err:     ValidateShellFilter(shellFilter),
+		})
+	}
+
+	for _, spec := range specs {
+		d := Diagnosis{Subject: fmt.Sprintf("%s filter %q", stream, spec)}
+		f, err := ParseFilter(spec)
+		if err != nil {
+			d.Err = err
+		} else if sh, ok := f.(ShellFilter); ok {
+			d.Err = ValidateShellFilter(sh.Command)
+		}
+		diagnoses = append(diagnoses, d)
+	}
+
+	return diagnoses
+}
+
+// DiagnoseCheckConfig validates the settings, filters, timeout, inputs, stdin
+// file and executable of a configured check.
+func DiagnoseCheckConfig(c CheckConfig) []Diagnosis {
+	name := c.Name
+	if name == "" {
+		name = c.Command
+	}
+	prefix := fmt.Sprintf("check %q", name)
+
+	var diagnoses []Diagnosis
+	diagnoses = append(diagnoses, DiagnoseFilters(prefix+" stdout", c.StdoutFilter, c.StdoutPipe)...)
+	diagnoses = append(diagnoses, DiagnoseFilters(prefix+" stderr", c.StderrFilter, c.StderrPipe)...)
+
+	if c.Timeout != "" {
+		_, err := time.ParseDuration(c.Timeout)
+		diagnoses = append(diagnoses, Diagnosis{Subject: fmt.Sprintf("%s timeout %q", prefix, c.Timeout), Err: err})
+	}
+
+	for _, pattern := range c.Inputs {
+		subject := fmt.Sprintf("%s input %q", prefix, pattern)
+		diagnoses = append(diagnoses, Diagnosis{Subject: subject, Err: ValidateGlob(pattern)})
+	}
+
+	if c.Foreach != "" {
+		_, err := ParseForeach(c.Foreach)
+		diagnoses = append(diagnoses, Diagnosis{Subject: fmt.Sprintf("%s foreach %q", prefix, c.Foreach), Err: err})
+	}
+
+	// Building the check validates the remaining settings, such as severity,
+	// exit codes and retries. It stops at the first error, which repeats one
+	// reported above when there is any.
+	if !slices.ContainsFunc(diagnoses, func(d Diagnosis) bool { return d.Err != nil }) {
+		_, err := c.Check(Check{})
+		diagnoses = append(diagnoses, Diagnosis{Subject: prefix + " settings", Err: err})
+	}
+
+	if c.Stdin != "" && c.Stdin != StdinPayload {
+		diagnoses = append(diagnoses, Diagnosis{
+			Subject: fmt.Sprintf("%s stdin %q", prefix, c.Stdin),
+			Err:     diagnoseStdinFile(c),
+		})
+	}
+
+	return append(diagnoses, DiagnoseCommand(c.Command))
+}
+
+// diagnoseStdinFile checks that the stdin file of c can be read, resolving a
+// relative path against the check's working directory like the executor does.
+func diagnoseStdinFile(c CheckConfig) error {
+	path := c.Stdin
+	if !filepath.IsAbs(path) && c.Cwd != "" {
+		path = filepath.Join(ExpandEnv(c.Cwd, nil), path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// DiagnoseCommand checks that the executable of cmdStr can be found.
+func DiagnoseCommand(cmdStr string) Diagnosis {
+	d := Diagnosis{Subject: fmt.Sprintf("command %q", cmdStr)}
+
+	parts := strings.Fields(cmdStr)
+	if len(parts) == 0 {
+		d.Err = errors.New("empty command")
+		return d
+	}
+
+	if _, err := exec.LookPath(parts[0]); err != nil {
+		d.Err = err
+	}
+
+	return d
+}
+
+// ValidateShellFilter checks the syntax of a shell filter and runs it once on
+// sample input. Exit code 1 without stderr output is accepted because tools
+// such as grep use it to report "no match".
+func ValidateShellFilter(command string) error {
+	// #nosec G204 - This is a CLI tool designed to execute user-provided filter commands
+	syntax := exec.Command("sh", "-n", "-c", command)
+	var syntaxErr bytes.Buffer
+	syntax.Stderr = &syntaxErr
+	if err := syntax.Run(); err != nil {
+		return &FilterError{
+			Stderr:  syntaxErr.String(),
+			Message: "invalid shell syntax",
+		}
+	}
+
+	_, err := ShellFilter{Command: command}.Apply(doctorSample)
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) {
+		return err
+	}
+	if filterErr.ExitCode == 1 && strings.TrimSpace(filterErr.Stderr) == "" {
+		return nil
+	}
+
+	// The subject already names the filter.
+	filterErr.Filter = ""
+	return filterErr
+}
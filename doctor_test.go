@@ -0,0 +1,133 @@
+package blocc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateShellFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		wantErr string
+	}{
+		{name: "valid filter", command: "sort | uniq"},
+		{name: "grep without match is accepted", command: "grep nothing-matches-this"},
+		{name: "syntax error", command: "sort |", wantErr: "invalid shell syntax"},
+		{name: "missing command", command: "nonexistentcommand123", wantErr: "exit status 127"},
+		{name: "failing with stderr", command: "echo broken >&2; exit 1", wantErr: "broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateShellFilter(tt.command)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateShellFilter() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateShellFilter() error = %v, want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiagnoseFilters(t *testing.T) {
+	diagnoses := DiagnoseFilters("stdout", "head -n 1", []string{"grep:ok", "awk:{print}", "sh:nonexistentcommand123"})
+
+	if len(diagnoses) != 4 {
+		t.Fatalf("DiagnoseFilters() returned %d diagnoses, want 4", len(diagnoses))
+	}
+
+	wantErr := []bool{false, false, true, true}
+	for i, d := range diagnoses {
+		if (d.Err != nil) != wantErr[i] {
+			t.Errorf("diagnosis %d (%s) error = %v, wantErr %v", i, d.Subject, d.Err, wantErr[i])
+		}
+		if !strings.HasPrefix(d.Subject, "stdout filter") {
+			t.Errorf("diagnosis %d subject = %q", i, d.Subject)
+		}
+	}
+}
+
+func TestDiagnoseCommand(t *testing.T) {
+	if d := DiagnoseCommand("echo hello"); d.Err != nil {
+		t.Errorf("DiagnoseCommand(echo) error = %v", d.Err)
+	}
+	if d := DiagnoseCommand("nonexistentcommand123 --flag"); d.Err == nil {
+		t.Error("DiagnoseCommand(nonexistent) expected error")
+	}
+	if d := DiagnoseCommand(""); d.Err == nil {
+		t.Error("DiagnoseCommand(empty) expected error")
+	}
+}
+
+func TestDiagnoseCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"input.txt": "data\n"})
+
+	tests := []struct {
+		name    string
+		config  CheckConfig
+		subject string
+		wantErr string
+	}{
+		{name: "valid", config: CheckConfig{Command: "true", Severity: "warn", Retries: 1}},
+		{
+			name:    "invalid severity",
+			config:  CheckConfig{Command: "true", Severity: "fatal"},
+			subject: "settings",
+			wantErr: "fatal",
+		},
+		{
+			name:    "negative retries",
+			config:  CheckConfig{Command: "true", Retries: -1},
+			subject: "settings",
+			wantErr: "retries must not be negative",
+		},
+		{
+			name:    "success and warning code",
+			config:  CheckConfig{Command: "true", SuccessCodes: []int{1}, WarnCodes: []int{1}},
+			subject: "settings",
+			wantErr: "both a success and a warning code",
+		},
+		{name: "payload stdin", config: CheckConfig{Command: "true", Stdin: StdinPayload}},
+		{name: "stdin file", config: CheckConfig{Command: "true", Stdin: "input.txt", Cwd: dir}},
+		{
+			name:    "missing stdin file",
+			config:  CheckConfig{Command: "true", Stdin: "missing.txt", Cwd: dir},
+			subject: `stdin "missing.txt"`,
+			wantErr: "no such file",
+		},
+		{
+			name:    "filter error is not repeated",
+			config:  CheckConfig{Command: "true", StdoutPipe: []string{"bogus:1"}, Severity: "fatal"},
+			subject: `stdout filter "bogus:1"`,
+			wantErr: "bogus",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failed []Diagnosis
+			for _, d := range DiagnoseCheckConfig(tt.config) {
+				if d.Err != nil {
+					failed = append(failed, d)
+				}
+			}
+
+			if tt.wantErr == "" {
+				if len(failed) > 0 {
+					t.Errorf("DiagnoseCheckConfig() = %+v, want no errors", failed)
+				}
+				return
+			}
+			if len(failed) != 1 || !strings.HasSuffix(failed[0].Subject, tt.subject) ||
+				!strings.Contains(failed[0].Err.Error(), tt.wantErr) {
+				t.Errorf("DiagnoseCheckConfig() = %+v, want one error of %s containing %q", failed, tt.subject, tt.wantErr)
+			}
+		})
+	}
+}
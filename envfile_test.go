@@ -0,0 +1,80 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	t.Setenv("BLOCC_TEST_HOME", "/home/test")
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "values",
+			content: `# comment
+PLAIN=value
+export EXPORTED=1
+
+SPACED = padded value # trailing comment
+SINGLE='$NOT_EXPANDED # kept'
+DOUBLE="line\nbreak \"quoted\""
+`,
+			want: map[string]string{
+				"PLAIN":    "value",
+				"EXPORTED": "1",
+				"SPACED":   "padded value",
+				"SINGLE":   "$NOT_EXPANDED # kept",
+				"DOUBLE":   "line\nbreak \"quoted\"",
+			},
+		},
+		{
+			name:    "expansion",
+			content: "DIR=${BLOCC_TEST_HOME}/app\nBIN=$DIR/bin\nQUOTED=\"$DIR\"\n",
+			want:    map[string]string{"DIR": "/home/test/app", "BIN": "/home/test/app/bin", "QUOTED": "/home/test/app"},
+		},
+		{name: "missing equals", content: "NOVALUE\n", wantErr: "line 1: want KEY=VALUE"},
+		{name: "invalid key", content: "A=1\n1A=2\n", wantErr: "line 2: want KEY=VALUE"},
+		{name: "unterminated quote", content: `A="open`, wantErr: "unterminated quote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnvFile(strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ParseEnvFile() error = %v, want contains %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseEnvFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEnvFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\nB\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadEnvFile(path); err == nil || !strings.Contains(err.Error(), path+": line 2") {
+		t.Errorf("LoadEnvFile() error = %v, want path and line", err)
+	}
+
+	if _, err := LoadEnvFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadEnvFile() expected error for missing file")
+	}
+}
@@ -3,166 +3,718 @@ package blocc
 import (
 	"bytes"
 	"context"
-	"os/exec"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
-	"sync"
+	"time"
+)
+
+// timeoutExitCode is reported for commands killed because their timeout expired,
+// matching timeout(1).
+const timeoutExitCode = 124
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusPassed    Status = "passed"
+	StatusFailed    Status = "failed"
+	StatusTimedOut  Status = "timedOut"
+	StatusCancelled Status = "cancelled"
+	StatusSkipped   Status = "skipped"
+	StatusCached    Status = "cached"
 )
 
 type Result struct {
+	Name     string `json:"name,omitempty"`
 	Command  string `json:"command"`
 	ExitCode int    `json:"exitCode"`
-	Stderr   string `json:"stderr,omitempty"`
-	Stdout   string `json:"stdout,omitempty"`
+	Status   Status `json:"status,omitempty"`
+	// Package is the workspace package the check ran in.
+	Package string `json:"package,omitempty"`
+	// SkipReason explains why a skipped check did not run.
+	SkipReason string `json:"skipReason,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	Stdout     string `json:"stdout,omitempty"`
+
+	StartedAt time.Time `json:"startedAt,omitzero"`
+	Duration  Duration  `json:"duration,omitzero"`
+	Dir       string    `json:"cwd,omitempty"`
+	// Env names the variables set for the check on top of the process
+	// environment. Their values may be secrets and are never recorded.
+	Env        []string `json:"env,omitempty"`
+	Executable string   `json:"executable,omitempty"`
+	Signal     string   `json:"signal,omitempty"`
+
+	// Severity is set for checks whose failure does not block.
+	Severity Severity `json:"severity,omitempty"`
+	// Attempts is how many times the command ran when it was retried.
+	Attempts int `json:"attempts,omitempty"`
+	// Flaky marks commands that passed only after retrying.
+	Flaky bool `json:"flaky,omitempty"`
+	// Unchanged marks failures whose output, omitted here, is identical to the
+	// previous run of the session.
+	Unchanged bool `json:"unchanged,omitempty"`
+
+	FilterErrors []FilterError `json:"filterErrors,omitempty"`
+
+	Truncated           bool `json:"truncated,omitempty"`
+	OriginalStdoutBytes int  `json:"originalStdoutBytes,omitempty"`
+	OriginalStderrBytes int  `json:"originalStderrBytes,omitempty"`
+	// StdoutBytes and StderrBytes count all output the command wrote, before
+	// normalization, filters and truncation.
+	StdoutBytes int64 `json:"stdoutBytes,omitempty"`
+	StderrBytes int64 `json:"stderrBytes,omitempty"`
 }
 
 type Executor struct {
-	includeStdout bool
-	stdoutFilter  string
-	stderrFilter  string
-	noStderr      bool
+	defaults          Check
+	filterErrorPolicy FilterErrorPolicy
+	normalizer        Normalizer
+	cache             *Cache
+	stopPolicy        StopPolicy
+	stream            *Streamer
+	runner            Runner
+	onStart           func(Check)
+	onResult          func(Result)
+	payload           *HookPayload
+	captureLimit      int64
+	// baseDir is the directory relative check directories are resolved against.
+	baseDir string
 }
 
 func NewExecutor(includeStdout bool, stdoutFilter, stderrFilter string, noStderr bool) *Executor {
+	stdoutPipeline, _ := NewPipeline(stdoutFilter, nil)
+	stderrPipeline, _ := NewPipeline(stderrFilter, nil)
+	return NewExecutorWithPipelines(includeStdout, stdoutPipeline, stderrPipeline, noStderr)
+}
+
+func NewExecutorWithPipelines(includeStdout bool, stdoutPipeline, stderrPipeline Pipeline, noStderr bool) *Executor {
+	cwd, _ := os.Getwd()
+	root := FindRepoRoot(cwd)
 	return &Executor{
-		includeStdout: includeStdout,
-		stdoutFilter:  stdoutFilter,
-		stderrFilter:  stderrFilter,
-		noStderr:      noStderr,
+		defaults: Check{
+			IncludeStdout:  includeStdout,
+			StdoutPipeline: stdoutPipeline,
+			StderrPipeline: stderrPipeline,
+			NoStderr:       noStderr,
+		},
+		filterErrorPolicy: FilterErrorAnnotate,
+		stopPolicy:        DefaultStopPolicy,
+		captureLimit:      DefaultCaptureLimit,
+		runner:            ExecRunner{},
+		normalizer:        NewNormalizer(root),
+		baseDir:           root,
+	}
+}
+
+// WithFilterErrorPolicy sets how failing filters are handled and returns the executor.
+func (e *Executor) WithFilterErrorPolicy(policy FilterErrorPolicy) *Executor {
+	e.filterErrorPolicy = policy
+	return e
+}
+
+// WithRaw disables output normalization for checks that do not override it and returns the executor.
+func (e *Executor) WithRaw(raw bool) *Executor {
+	e.defaults.Raw = raw
+	return e
+}
+
+// WithCache enables result caching for checks with inputs and returns the executor.
+// A nil cache disables caching.
+func (e *Executor) WithCache(cache *Cache) *Executor {
+	e.cache = cache
+	return e
+}
+
+// WithStopPolicy sets when a run stops early and returns the executor.
+func (e *Executor) WithStopPolicy(policy StopPolicy) *Executor {
+	e.stopPolicy = policy
+	return e
+}
+
+// WithStream tees the output of commands to w while they run, line by line and
+// prefixed with their names, and returns the executor. A nil w disables streaming.
+func (e *Executor) WithStream(w io.Writer) *Executor {
+	e.stream = nil
+	if w != nil {
+		e.stream = NewStreamer(w)
+	}
+	return e
+}
+
+// WithRunner sets how the processes of checks are run and returns the executor.
+func (e *Executor) WithRunner(runner Runner) *Executor {
+	e.runner = runner
+	return e
+}
+
+// OnStart sets a function called when a check starts and returns the executor.
+func (e *Executor) OnStart(fn func(Check)) *Executor {
+	e.onStart = fn
+	return e
+}
+
+// OnResult sets a function called with the result of every check, including
+// skipped ones, as soon as it is known and returns the executor.
+func (e *Executor) OnResult(fn func(Result)) *Executor {
+	e.onResult = fn
+	return e
+}
+
+// WithCaptureLimit sets how many bytes of each output stream a command keeps
+// in memory and returns the executor. Longer output keeps its head and tail and
+// spills to disk for filters.
+func (e *Executor) WithCaptureLimit(limit int64) *Executor {
+	e.captureLimit = limit
+	return e
+}
+
+// WithHookPayload passes the hook payload to commands and filters as BLOCC_*
+// environment variables, and to the stdin of checks that ask for it, and returns
+// the executor. A nil payload passes nothing.
+func (e *Executor) WithHookPayload(payload *HookPayload) *Executor {
+	e.payload = payload
+	return e
+}
+
+// WithStdin sets the stdin of checks that do not override it and returns the executor.
+func (e *Executor) WithStdin(stdin string) *Executor {
+	e.defaults.Stdin = stdin
+	return e
+}
+
+// WithEnv sets environment variables for checks that do not override them and returns the executor.
+func (e *Executor) WithEnv(env map[string]string) *Executor {
+	e.defaults.Env = env
+	return e
+}
+
+// WithBaseDir sets the directory relative check directories are resolved against
+// and returns the executor. It defaults to the repository root. Paths in output
+// are made relative to the repository root of dir.
+func (e *Executor) WithBaseDir(dir string) *Executor {
+	e.baseDir = dir
+	e.normalizer = NewNormalizer(FindRepoRoot(dir))
+	return e
+}
+
+// BaseDir returns the directory relative check directories are resolved against.
+func (e *Executor) BaseDir() string {
+	return e.baseDir
+}
+
+// resolveDir returns dir resolved against the base directory. An empty dir
+// stays empty, running the command in the current directory.
+func (e *Executor) resolveDir(dir string) string {
+	if dir == "" || filepath.IsAbs(dir) || e.baseDir == "" {
+		return dir
+	}
+	return filepath.Join(e.baseDir, dir)
+}
+
+// Defaults returns the settings applied to checks that do not override them.
+func (e *Executor) Defaults() Check {
+	return e.defaults
+}
+
+// NewCheck returns a check running command with the executor's default settings.
+func (e *Executor) NewCheck(command string) Check {
+	check := e.defaults
+	check.Command = command
+	return check
+}
+
+func (e *Executor) checksFor(commands []string) []Check {
+	checks := make([]Check, len(commands))
+	for i, cmdStr := range commands {
+		checks[i] = e.NewCheck(cmdStr)
 	}
+	return checks
 }
 
+// ExecuteSequential runs commands one after another and returns a result for
+// every command. Use FailedResults to select the failures.
 func (e *Executor) ExecuteSequential(commands []string) ([]Result, error) {
-	var failedResults []Result
-
-	for _, cmdStr := range commands {
-		result := e.executeCommand(cmdStr)
-		if result.ExitCode != 0 {
-			failedResults = append(failedResults, result)
-			if result.ExitCode == 2 {
-				// Exit immediately if exit code is 2
-				return failedResults, nil
-			}
+	return e.ExecuteChecksSequential(e.checksFor(commands))
+}
+
+// ExecuteParallel runs commands concurrently and returns a result for every
+// command. Use FailedResults to select the failures.
+func (e *Executor) ExecuteParallel(commands []string) ([]Result, error) {
+	return e.ExecuteChecksParallel(e.checksFor(commands))
+}
+
+// FailedResults returns the results that should block: failed or timed out
+// checks with block severity.
+func FailedResults(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Blocking() {
+			failed = append(failed, r)
 		}
 	}
+	return failed
+}
 
-	return failedResults, nil
+// WarningResults returns the failed or timed out checks with warn severity.
+func WarningResults(results []Result) []Result {
+	var warnings []Result
+	for _, r := range results {
+		if r.Failed() && r.Severity == SeverityWarn {
+			warnings = append(warnings, r)
+		}
+	}
+	return warnings
 }
 
-func (e *Executor) ExecuteParallel(commands []string) ([]Result, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	resultChan := make(chan Result, len(commands))
-	var wg sync.WaitGroup
-
-	for _, cmdStr := range commands {
-		wg.Add(1)
-		go func(cmd string) {
-			defer wg.Done()
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				result := e.executeCommand(cmd)
-				if result.ExitCode == 2 {
-					cancel() // Cancel other goroutines
-				}
-				resultChan <- result
-			}
-		}(cmdStr)
+// Blocking reports whether the result failed a check with block severity.
+func (r Result) Blocking() bool {
+	return r.Failed() && (r.Severity == "" || r.Severity == SeverityBlock)
+}
+
+// Failed reports whether the check failed or timed out.
+func (r Result) Failed() bool {
+	return r.Status == StatusFailed || r.Status == StatusTimedOut
+}
+
+// ExecuteChecksSequential runs checks one at a time, in order unless their
+// needs require otherwise, and returns a result for every check.
+func (e *Executor) ExecuteChecksSequential(checks []Check) ([]Result, error) {
+	return e.ExecuteChecks(checks, 1)
+}
+
+// ExecuteChecksParallel runs every check as soon as its needs are met and
+// returns a result for every check.
+func (e *Executor) ExecuteChecksParallel(checks []Check) ([]Result, error) {
+	return e.ExecuteChecks(checks, 0)
+}
+
+// collectFilterErrors appends the filter failures of result when they should abort the run.
+func (e *Executor) collectFilterErrors(errs []error, result Result) []error {
+	if e.filterErrorPolicy != FilterErrorFail {
+		return errs
+	}
+	for i := range result.FilterErrors {
+		errs = append(errs, fmt.Errorf("%s: %w", result.Command, &result.FilterErrors[i]))
 	}
+	return errs
+}
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+// runCheck returns the cached result of check when its inputs are unchanged
+// since a successful run, and executes it otherwise.
+func (e *Executor) runCheck(ctx context.Context, check Check) Result {
+	check.Dir = e.resolveDir(check.Dir)
+	if e.cache == nil || len(check.Inputs) == 0 {
+		return e.executeWithRetries(ctx, check)
+	}
 
-	var failedResults []Result
-	for result := range resultChan {
-		if result.ExitCode != 0 {
-			failedResults = append(failedResults, result)
+	if key, err := cacheKey(check, cacheEnv(check)); err == nil {
+		if cached, ok := e.cache.Lookup(key); ok {
+			result := newResult(check)
+			result.Status = StatusCached
+			result.StartedAt = time.Now()
+			result.Dir = cached.Dir
+			result.Env = envNames(check.Env)
+			result.Executable = cached.Executable
+			result.Stdout = cached.Stdout
+			return result
 		}
 	}
 
-	return failedResults, nil
+	result := e.executeWithRetries(ctx, check)
+	if result.Status != StatusPassed {
+		return result
+	}
+
+	// Hash the inputs after the run so that checks rewriting their inputs,
+	// such as formatters, hit the cache next time.
+	if key, err := cacheKey(check, cacheEnv(check)); err == nil {
+		_ = e.cache.Store(key, result)
+	}
+	return result
 }
 
-func (e *Executor) executeCommand(cmdStr string) Result {
-	parts := strings.Fields(cmdStr)
-	if len(parts) == 0 {
-		result := Result{
-			Command:  cmdStr,
-			ExitCode: 1,
-			Stderr:   "empty command",
+// executeWithRetries runs check until it passes or its retries are used up.
+// The returned result is the last attempt's, timed from the first attempt.
+func (e *Executor) executeWithRetries(ctx context.Context, check Check) Result {
+	result := e.executeCheck(ctx, check)
+	if check.Retries <= 0 {
+		return result
+	}
+
+	startedAt := result.StartedAt
+	delay := check.RetryDelay
+	attempts := 1
+
+	for attempts <= check.Retries && result.Failed() {
+		if !sleepContext(ctx, delay) {
+			break
 		}
-		if e.noStderr {
-			result.Stderr = ""
+		if check.RetryBackoff > 0 {
+			delay = time.Duration(float64(delay) * check.RetryBackoff)
 		}
-		return result
+
+		result = e.executeCheck(ctx, check)
+		attempts++
 	}
 
-	// #nosec G204 - This is a CLI tool designed to execute user-provided commands
-	cmd := exec.Command(parts[0], parts[1:]...)
+	if attempts > 1 {
+		result.Attempts = attempts
+		result.Flaky = result.Status == StatusPassed
+		result.Duration = Duration(time.Since(startedAt))
+		result.StartedAt = startedAt
+	}
+	return result
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// sleepContext waits for d and reports whether ctx is still active.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
 
-	err := cmd.Run()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-	// Apply filters to outputs
-	filteredStderr := e.applyFilter(stderr.String(), e.stderrFilter)
-	filteredStdout := stdout.String()
-	if e.includeStdout {
-		filteredStdout = e.applyFilter(stdout.String(), e.stdoutFilter)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
+}
 
-	result := Result{
-		Command:  cmdStr,
-		ExitCode: 0,
-		Stderr:   filteredStderr,
+// newResult returns a result identifying check, without any outcome.
+func newResult(check Check) Result {
+	result := Result{Command: check.Command, Package: check.Package}
+	if check.Severity != SeverityBlock {
+		result.Severity = check.Severity
+	}
+	if check.Name != "" && check.Name != check.Command {
+		result.Name = check.Name
 	}
+	return result
+}
+
+func skippedResult(check Check, reason string) Result {
+	result := newResult(check)
+	result.Status = StatusSkipped
+	result.SkipReason = reason
+	return result
+}
+
+func (e *Executor) executeCheck(ctx context.Context, check Check) (result Result) {
+	result = newResult(check)
+	result.Status = StatusPassed
+	result.StartedAt = time.Now()
+	result.Dir = workingDir(check.Dir)
+	result.Env = envNames(check.Env)
+	defer func() {
+		result.Duration = Duration(time.Since(result.StartedAt))
+	}()
 
-	if e.noStderr {
-		result.Stderr = ""
+	parts := strings.Fields(check.Command)
+	if len(parts) == 0 {
+		result.ExitCode = 1
+		result.Status = StatusFailed
+		if !check.NoStderr {
+			result.Stderr = "empty command"
+		}
+		return result
 	}
 
-	if e.includeStdout {
-		result.Stdout = filteredStdout
+	if check.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, check.Timeout)
+		defer cancel()
 	}
 
+	process := Process{Args: parts, Dir: check.Dir, Env: e.commandEnv(check)}
+
+	stdin, err := e.commandStdin(check)
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			result.ExitCode = exitError.ExitCode()
+		result.ExitCode = 1
+		result.Status = StatusFailed
+		if !check.NoStderr {
+			result.Stderr = err.Error()
+		}
+		return result
+	}
+	if stdin != nil {
+		defer stdin.Close()
+		process.Stdin = stdin
+	}
+
+	stdout, stderr := NewCapture(e.captureLimit, DefaultSpillLimit), NewCapture(e.captureLimit, DefaultSpillLimit)
+	defer stdout.Close()
+	defer stderr.Close()
+	process.Stdout = stdout
+	process.Stderr = stderr
+	if e.stream != nil {
+		// Separate writers keep partial stdout and stderr lines apart.
+		streamOut, streamErr := e.stream.Writer(resultTitle(result)), e.stream.Writer(resultTitle(result))
+		defer streamOut.Close()
+		defer streamErr.Close()
+		process.Stdout = io.MultiWriter(stdout, streamOut)
+		process.Stderr = io.MultiWriter(stderr, streamErr)
+	}
+
+	status, err := e.runner.Run(ctx, process)
+	result.Executable = status.Executable
+	result.Signal = status.Signal
+
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	cancelled := errors.Is(ctx.Err(), context.Canceled)
+	if timedOut {
+		fmt.Fprintf(stderr, "\nblocc: command timed out after %s\n", check.Timeout)
+	}
+
+	// Apply filters to outputs
+	result.StdoutBytes, result.StderrBytes = stdout.Len(), stderr.Len()
+	if !check.NoStderr {
+		result.Stderr = e.filterCapture(&result, check, "stderr", stderr, check.StderrPipeline)
+	}
+
+	if check.IncludeStdout {
+		result.Stdout = e.filterCapture(&result, check, "stdout", stdout, check.StdoutPipeline)
+	}
+
+	if err != nil || status.Code != 0 {
+		result.Status = StatusFailed
+		if err == nil && !timedOut {
+			result.ExitCode = status.Code
+		} else if timedOut {
+			result.Status = StatusTimedOut
+			result.ExitCode = timeoutExitCode
 		} else {
 			result.ExitCode = 1
 			// If there's no stderr output, use the error message
-			if result.Stderr == "" && !e.noStderr {
+			if result.Stderr == "" && !check.NoStderr {
 				result.Stderr = err.Error()
 			}
 		}
+		if cancelled {
+			result.Status = StatusCancelled
+		}
+		if result.Status == StatusFailed && err == nil {
+			mapExitCode(&result, check)
+		}
 	}
 
 	return result
 }
 
-func (e *Executor) applyFilter(input, filterCmd string) string {
-	if filterCmd == "" || input == "" {
-		return input
+// mapExitCode applies the check's success and warning exit codes to a failed result.
+func mapExitCode(result *Result, check Check) {
+	switch {
+	case slices.Contains(check.SuccessCodes, result.ExitCode):
+		result.Status = StatusPassed
+	case slices.Contains(check.WarnCodes, result.ExitCode) && result.Severity == "":
+		result.Severity = SeverityWarn
 	}
+}
 
-	// #nosec G204 - This is a CLI tool designed to execute user-provided filter commands
-	cmd := exec.Command("sh", "-c", filterCmd)
-	cmd.Stdin = strings.NewReader(input)
+// workingDir returns the absolute directory a command runs in.
+func workingDir(dir string) string {
+	if dir == "" {
+		cwd, _ := os.Getwd()
+		return cwd
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
+// commandEnv returns the variables added to the inherited environment of check.
+func (e *Executor) commandEnv(check Check) []string {
+	var env []string
+	if !check.Raw {
+		env = append(env, envList(NormalizeEnv)...)
+	}
+	env = append(env, e.payloadEnv()...)
+	return append(env, envList(check.Env)...)
+}
 
-	err := cmd.Run()
+// cacheEnv returns the variables of commandEnv that identify a cached result.
+// The payload variables change with every session and edited file, so they
+// are left out to keep the cache useful across sessions.
+func cacheEnv(check Check) []string {
+	var env []string
+	if !check.Raw {
+		env = append(env, envList(NormalizeEnv)...)
+	}
+	return append(env, envList(check.Env)...)
+}
+
+// payloadEnv returns the BLOCC_* variables describing the hook payload.
+func (e *Executor) payloadEnv() []string {
+	if e.payload == nil {
+		return nil
+	}
+	return envList(e.payload.Env())
+}
+
+// commandStdin opens the input of check, or returns nil when it has none.
+func (e *Executor) commandStdin(check Check) (io.ReadCloser, error) {
+	switch check.Stdin {
+	case "":
+		return nil, nil
+	case StdinPayload:
+		if e.payload == nil {
+			return nil, nil
+		}
+		return io.NopCloser(bytes.NewReader(e.payload.Raw)), nil
+	}
+
+	path := check.Stdin
+	if !filepath.IsAbs(path) && check.Dir != "" {
+		path = filepath.Join(check.Dir, path)
+	}
+	f, err := os.Open(path)
 	if err != nil {
-		// If filter command fails, return original input
+		return nil, fmt.Errorf("blocc: cannot read stdin: %w", err)
+	}
+	return f, nil
+}
+
+func (e *Executor) normalize(check Check, output string) string {
+	if check.Raw {
+		return output
+	}
+	return e.normalizer.Normalize(output)
+}
+
+// envNames returns the sorted names of env.
+func envNames(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(env))
+}
+
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// filterCapture returns the normalized and filtered output of a stream. When
+// the output exceeded the capture limit, a first filter stage that can read
+// from a reader sees all of it, read back from disk, and its own output is
+// kept within the capture limit; otherwise filters see the head and tail kept
+// in memory.
+func (e *Executor) filterCapture(result *Result, check Check, stream string, c *Capture, pipeline Pipeline) string {
+	pipeline = pipeline.WithEnv(e.payloadEnv())
+	if !c.Truncated() {
+		return e.applyFilter(result, stream, e.normalize(check, c.String()), pipeline)
+	}
+
+	if first, ok := firstReaderFilter(pipeline); ok {
+		if r, err := c.Reader(); err == nil {
+			normalized := e.normalizeReader(check, r)
+			output := NewCapture(e.captureLimit, 0)
+			err := first.ApplyReader(normalized, output)
+			normalized.Close()
+			if err == nil {
+				if output.Truncated() {
+					result.Truncated = true
+				}
+				return e.applyFilter(result, stream, output.String(), pipeline[1:])
+			}
+			if e.filterErrorPolicy != FilterErrorFallback {
+				result.FilterErrors = append(result.FilterErrors, newFilterError(stream, err))
+			}
+			result.Truncated = true
+			return e.normalize(check, c.String())
+		}
+	}
+
+	result.Truncated = true
+	return e.applyFilter(result, stream, e.normalize(check, c.String()), pipeline)
+}
+
+func firstReaderFilter(pipeline Pipeline) (ReaderFilter, bool) {
+	if len(pipeline) == 0 {
+		return nil, false
+	}
+	f, ok := pipeline[0].(ReaderFilter)
+	return f, ok
+}
+
+// normalizeReader normalizes r in chunks of whole lines. Closing the returned
+// reader stops reading r.
+func (e *Executor) normalizeReader(check Check, r io.Reader) io.ReadCloser {
+	if check.Raw {
+		return io.NopCloser(r)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		buf := make([]byte, 64<<10)
+		n := 0
+		for {
+			m, err := r.Read(buf[n:])
+			n += m
+
+			// Normalize up to the last line break, or the whole buffer when
+			// it is full or the input ended.
+			end := bytes.LastIndexByte(buf[:n], '\n') + 1
+			if err != nil || end == 0 && n == len(buf) {
+				end = n
+			}
+			if end > 0 {
+				if _, err := io.WriteString(pw, e.normalizer.Normalize(string(buf[:end]))); err != nil {
+					return
+				}
+				n = copy(buf, buf[end:n])
+			}
+
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+func (e *Executor) applyFilter(result *Result, stream, input string, pipeline Pipeline) string {
+	if len(pipeline) == 0 || input == "" {
 		return input
 	}
 
-	return out.String()
+	output, err := pipeline.Apply(input)
+	if err != nil {
+		// If a filter stage fails, return original input
+		if e.filterErrorPolicy != FilterErrorFallback {
+			result.FilterErrors = append(result.FilterErrors, newFilterError(stream, err))
+		}
+		return input
+	}
+
+	return output
+}
+
+func newFilterError(stream string, err error) FilterError {
+	var filterErr *FilterError
+	if errors.As(err, &filterErr) {
+		fe := *filterErr
+		fe.Stream = stream
+		return fe
+	}
+	return FilterError{Stream: stream, Message: err.Error()}
 }
@@ -1,10 +1,52 @@
-package blocc
+package blocc_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/blocctest"
 )
 
+// newRunner returns a fake runner scripting the commands used by these tests.
+func newRunner() *blocctest.Runner {
+	return blocctest.NewRunner().
+		On("echo hello", blocctest.Response{Stdout: "hello\n"}).
+		On("echo world", blocctest.Response{Stdout: "world\n"}).
+		On("echo hi", blocctest.Response{Stdout: "hi\n"}).
+		On("true").
+		On("false", blocctest.Response{ExitCode: 1})
+}
+
+// executeCheck runs check alone on executor and returns its result.
+func executeCheck(ctx context.Context, executor *blocc.Executor, check blocc.Check) blocc.Result {
+	results, _ := executor.ExecuteChecksContext(ctx, []blocc.Check{check}, 1)
+	return results[0]
+}
+
+// executeCommand runs command alone with the executor defaults.
+func executeCommand(executor *blocc.Executor, command string) blocc.Result {
+	return executeCheck(context.Background(), executor, executor.NewCheck(command))
+}
+
+// lookupEnv returns the value env gives key, the last one winning like in a process environment.
+func lookupEnv(env []string, key string) (string, bool) {
+	value, found := "", false
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			value, found = v, true
+		}
+	}
+	return value, found
+}
+
 func TestExecuteCommand(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -45,8 +87,8 @@ func TestExecuteCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(false, "", "", false)
-			result := executor.executeCommand(tt.command)
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(newRunner())
+			result := executeCommand(executor, tt.command)
 
 			if result.ExitCode != tt.wantExitCode {
 				t.Errorf("executeCommand() exitCode = %v, want %v", result.ExitCode, tt.wantExitCode)
@@ -82,8 +124,8 @@ func TestExecuteCommandWithStdout(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(true, "", "", false) // Enable stdout
-			result := executor.executeCommand(tt.command)
+			executor := blocc.NewExecutor(true, "", "", false).WithRunner(newRunner()) // Enable stdout
+			result := executeCommand(executor, tt.command)
 
 			if result.ExitCode != tt.wantExitCode {
 				t.Errorf("executeCommand() exitCode = %v, want %v", result.ExitCode, tt.wantExitCode)
@@ -102,34 +144,38 @@ func TestExecuteCommandWithStdout(t *testing.T) {
 
 func TestExecuteSequential(t *testing.T) {
 	tests := []struct {
-		name        string
-		commands    []string
-		wantResults int
+		name       string
+		commands   []string
+		wantFailed int
 	}{
 		{
-			name:        "all success commands",
-			commands:    []string{"echo hello", "echo world"},
-			wantResults: 0,
+			name:       "all success commands",
+			commands:   []string{"echo hello", "echo world"},
+			wantFailed: 0,
 		},
 		{
-			name:        "one failing command",
-			commands:    []string{"echo hello", "false"},
-			wantResults: 1,
+			name:       "one failing command",
+			commands:   []string{"echo hello", "false"},
+			wantFailed: 1,
 		},
 		{
-			name:        "true and false commands",
-			commands:    []string{"true", "false", "echo hello"},
-			wantResults: 1,
+			name:       "true and false commands",
+			commands:   []string{"true", "false", "echo hello"},
+			wantFailed: 1,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(false, "", "", false)
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(newRunner())
 			results, _ := executor.ExecuteSequential(tt.commands)
 
-			if len(results) != tt.wantResults {
-				t.Errorf("ExecuteSequential() results count = %v, want %v", len(results), tt.wantResults)
+			if len(results) != len(tt.commands) {
+				t.Errorf("ExecuteSequential() results count = %v, want %v", len(results), len(tt.commands))
+			}
+
+			if failed := blocc.FailedResults(results); len(failed) != tt.wantFailed {
+				t.Errorf("ExecuteSequential() failed count = %v, want %v", len(failed), tt.wantFailed)
 			}
 		})
 	}
@@ -137,29 +183,33 @@ func TestExecuteSequential(t *testing.T) {
 
 func TestExecuteParallel(t *testing.T) {
 	tests := []struct {
-		name           string
-		commands       []string
-		wantMinResults int
+		name          string
+		commands      []string
+		wantMinFailed int
 	}{
 		{
-			name:           "all success commands",
-			commands:       []string{"echo hello", "echo world"},
-			wantMinResults: 0,
+			name:          "all success commands",
+			commands:      []string{"echo hello", "echo world"},
+			wantMinFailed: 0,
 		},
 		{
-			name:           "mixed success and failure",
-			commands:       []string{"echo hello", "false", "echo world"},
-			wantMinResults: 1,
+			name:          "mixed success and failure",
+			commands:      []string{"echo hello", "false", "echo world"},
+			wantMinFailed: 1,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(false, "", "", false)
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(newRunner())
 			results, _ := executor.ExecuteParallel(tt.commands)
 
-			if len(results) < tt.wantMinResults {
-				t.Errorf("ExecuteParallel() results count = %v, want at least %v", len(results), tt.wantMinResults)
+			if len(results) != len(tt.commands) {
+				t.Errorf("ExecuteParallel() results count = %v, want %v", len(results), len(tt.commands))
+			}
+
+			if failed := blocc.FailedResults(results); len(failed) < tt.wantMinFailed {
+				t.Errorf("ExecuteParallel() failed count = %v, want at least %v", len(failed), tt.wantMinFailed)
 			}
 		})
 	}
@@ -211,8 +261,8 @@ func TestExecuteCommandWithFilters(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(tt.includeStdout, tt.stdoutFilter, tt.stderrFilter, false)
-			result := executor.executeCommand(tt.command)
+			executor := blocc.NewExecutor(tt.includeStdout, tt.stdoutFilter, tt.stderrFilter, false).WithRunner(newRunner())
+			result := executeCommand(executor, tt.command)
 
 			if result.Stdout != tt.wantStdout {
 				t.Errorf("executeCommand() stdout = %v, want %v", result.Stdout, tt.wantStdout)
@@ -236,7 +286,7 @@ func TestNoStderrOption(t *testing.T) {
 			name:       "stderr included by default",
 			command:    "false",
 			noStderr:   false,
-			wantStderr: "",
+			wantStderr: "failed\n",
 		},
 		{
 			name:       "stderr excluded with no-stderr option",
@@ -260,8 +310,9 @@ func TestNoStderrOption(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			executor := NewExecutor(false, "", "", tt.noStderr)
-			result := executor.executeCommand(tt.command)
+			runner := blocctest.NewRunner().On("false", blocctest.Response{Stderr: "failed\n", ExitCode: 1})
+			executor := blocc.NewExecutor(false, "", "", tt.noStderr).WithRunner(runner)
+			result := executeCommand(executor, tt.command)
 
 			if result.Stderr != tt.wantStderr {
 				t.Errorf("executeCommand() stderr = %v, want %v", result.Stderr, tt.wantStderr)
@@ -269,3 +320,554 @@ func TestNoStderrOption(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterErrorPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           blocc.FilterErrorPolicy
+		wantFilterErrors int
+		wantErr          bool
+	}{
+		{
+			name:             "annotate records filter errors",
+			policy:           blocc.FilterErrorAnnotate,
+			wantFilterErrors: 1,
+		},
+		{
+			name:             "fallback hides filter errors",
+			policy:           blocc.FilterErrorFallback,
+			wantFilterErrors: 0,
+		},
+		{
+			name:             "fail aborts the run",
+			policy:           blocc.FilterErrorFail,
+			wantFilterErrors: 1,
+			wantErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := blocc.NewExecutor(true, "echo filter-broke >&2; exit 3", "", false).
+				WithRunner(newRunner()).
+				WithFilterErrorPolicy(tt.policy)
+
+			result := executeCommand(executor, "echo hello")
+			if result.Stdout != "hello\n" {
+				t.Errorf("executeCommand() stdout = %q, want original output", result.Stdout)
+			}
+
+			if len(result.FilterErrors) != tt.wantFilterErrors {
+				t.Fatalf("executeCommand() filterErrors = %v, want %d", result.FilterErrors, tt.wantFilterErrors)
+			}
+
+			if tt.wantFilterErrors > 0 {
+				fe := result.FilterErrors[0]
+				if fe.Stream != "stdout" || fe.ExitCode != 3 || !strings.Contains(fe.Stderr, "filter-broke") {
+					t.Errorf("unexpected filter error: %+v", fe)
+				}
+			}
+
+			_, err := executor.ExecuteSequential([]string{"echo hello"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExecuteSequential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name         string
+		check        blocc.Check
+		wantExitCode int
+		wantStderr   string
+		wantDir      string
+		wantEnv      string
+	}{
+		{
+			name:         "working directory",
+			check:        blocc.Check{Command: "make build", Dir: dir},
+			wantExitCode: 0,
+			wantDir:      dir,
+		},
+		{
+			name:         "environment variables",
+			check:        blocc.Check{Command: "make build", Env: map[string]string{"BLOCC_TEST_VALUE": "42"}},
+			wantExitCode: 0,
+			wantEnv:      "42",
+		},
+		{
+			name:         "timeout",
+			check:        blocc.Check{Command: "make slow", Timeout: 100 * time.Millisecond},
+			wantExitCode: 124,
+			wantStderr:   "timed out after 100ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := blocctest.NewRunner().On("make build").On("make slow", blocctest.Response{Block: true})
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+			result := executeCheck(context.Background(), executor, tt.check)
+
+			if result.ExitCode != tt.wantExitCode {
+				t.Errorf("executeCheck() exitCode = %v, want %v", result.ExitCode, tt.wantExitCode)
+			}
+
+			if tt.wantStderr != "" && !strings.Contains(result.Stderr, tt.wantStderr) {
+				t.Errorf("executeCheck() stderr = %q, want contains %q", result.Stderr, tt.wantStderr)
+			}
+
+			call := runner.Calls()[0]
+			if call.Dir != tt.wantDir {
+				t.Errorf("executeCheck() ran in %q, want %q", call.Dir, tt.wantDir)
+			}
+			if value, _ := lookupEnv(call.Env, "BLOCC_TEST_VALUE"); value != tt.wantEnv {
+				t.Errorf("executeCheck() BLOCC_TEST_VALUE = %q, want %q", value, tt.wantEnv)
+			}
+			if killed := tt.wantExitCode == 124; call.Killed != killed {
+				t.Errorf("executeCheck() killed = %v, want %v", call.Killed, killed)
+			}
+		})
+	}
+}
+
+func TestExecuteChecksSeverity(t *testing.T) {
+	executor := blocc.NewExecutor(false, "", "", false).WithRunner(newRunner())
+	checks := []blocc.Check{
+		{Name: "optional", Command: "false", Severity: blocc.SeverityIgnore},
+		{Name: "style", Command: "false", Severity: blocc.SeverityWarn},
+		{Name: "required", Command: "false"},
+	}
+
+	results, _ := executor.ExecuteChecksSequential(checks)
+	if failed := blocc.FailedResults(results); len(results) != 3 || len(failed) != 1 || failed[0].Name != "required" {
+		t.Errorf("ExecuteChecksSequential() results = %+v, want only the required check to fail", results)
+	}
+	if warnings := blocc.WarningResults(results); len(warnings) != 1 || warnings[0].Name != "style" {
+		t.Errorf("WarningResults() = %+v, want the style check", warnings)
+	}
+
+	results, _ = executor.ExecuteChecksParallel(checks)
+	if failed := blocc.FailedResults(results); len(results) != 3 || len(failed) != 1 || failed[0].Name != "required" {
+		t.Errorf("ExecuteChecksParallel() results = %+v, want only the required check to fail", results)
+	}
+}
+
+func TestExecuteCheckMetadata(t *testing.T) {
+	dir := t.TempDir()
+	executor := blocc.NewExecutor(false, "", "", false).
+		WithRunner(newRunner().On("make slow", blocctest.Response{Block: true}))
+
+	tests := []struct {
+		name       string
+		check      blocc.Check
+		wantStatus blocc.Status
+		wantSignal string
+	}{
+		{name: "passed", check: blocc.Check{Command: "true", Dir: dir}, wantStatus: blocc.StatusPassed},
+		{name: "failed", check: blocc.Check{Command: "false", Dir: dir}, wantStatus: blocc.StatusFailed},
+		{
+			name:       "timed out",
+			check:      blocc.Check{Command: "make slow", Dir: dir, Timeout: 100 * time.Millisecond},
+			wantStatus: blocc.StatusTimedOut,
+			wantSignal: "killed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			result := executeCheck(context.Background(), executor, tt.check)
+
+			if result.Status != tt.wantStatus || result.Signal != tt.wantSignal {
+				t.Errorf("executeCheck() status = %q, signal = %q, want %q, %q",
+					result.Status, result.Signal, tt.wantStatus, tt.wantSignal)
+			}
+
+			if result.StartedAt.Before(before) || result.Duration <= 0 {
+				t.Errorf("executeCheck() startedAt = %v, duration = %v", result.StartedAt, result.Duration)
+			}
+
+			if result.Dir != dir || !filepath.IsAbs(result.Executable) {
+				t.Errorf("executeCheck() cwd = %q, executable = %q", result.Dir, result.Executable)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if result := executeCheck(ctx, executor, blocc.Check{Command: "make slow"}); result.Status != blocc.StatusCancelled {
+		t.Errorf("executeCheck() status = %q, want %q", result.Status, blocc.StatusCancelled)
+	}
+}
+
+func TestExecuteChecksStopOnExitCode2(t *testing.T) {
+	runner := newRunner().On("./exit2.sh", blocctest.Response{ExitCode: 2})
+	executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+	checks := []blocc.Check{
+		{Command: "./exit2.sh"},
+		{Command: "true"},
+	}
+
+	results, _ := executor.ExecuteChecksSequential(checks)
+	if len(results) != 2 || results[0].ExitCode != 2 || results[1].Status != blocc.StatusSkipped {
+		t.Errorf("ExecuteChecksSequential() results = %+v, want the second check skipped", results)
+	}
+
+	summary := blocc.NewSummary(results, time.Second)
+	if summary.Total != 2 || summary.Failed != 1 || summary.Skipped != 1 || summary.Passed != 0 {
+		t.Errorf("NewSummary() = %+v", summary)
+	}
+}
+
+func TestExecuteChecksResolvesDir(t *testing.T) {
+	base := t.TempDir()
+	if err := os.Mkdir(filepath.Join(base, "api"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newRunner()
+	executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner).WithBaseDir(base)
+	checks := []blocc.Check{
+		{Name: "relative", Command: "true", Dir: "api", Env: map[string]string{"MODE": "test"}},
+		{Name: "absolute", Command: "true", Dir: base},
+	}
+
+	results, err := executor.ExecuteChecksSequential(checks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relative, absolute := results[0], results[1]
+	if want, ran := filepath.Join(base, "api"), runner.Calls()[0].Dir; relative.Dir != want || ran != want {
+		t.Errorf("relative check ran in %q (cwd %q), want %q", ran, relative.Dir, want)
+	}
+	if !reflect.DeepEqual(relative.Env, []string{"MODE"}) {
+		t.Errorf("relative check env = %v, want the variable name recorded", relative.Env)
+	}
+	if absolute.Dir != base || absolute.Env != nil {
+		t.Errorf("absolute check = %+v, want cwd %q without env", absolute, base)
+	}
+}
+
+func TestExecuteCheckExitCodeMapping(t *testing.T) {
+	executor := blocc.NewExecutor(false, "", "", false).WithRunner(newRunner())
+
+	tests := []struct {
+		name         string
+		check        blocc.Check
+		wantStatus   blocc.Status
+		wantSeverity blocc.Severity
+	}{
+		{name: "success code", check: blocc.Check{Command: "false", SuccessCodes: []int{1}}, wantStatus: blocc.StatusPassed},
+		{
+			name:         "warning code",
+			check:        blocc.Check{Command: "false", WarnCodes: []int{1}},
+			wantStatus:   blocc.StatusFailed,
+			wantSeverity: blocc.SeverityWarn,
+		},
+		{
+			name:         "warning code keeps ignore",
+			check:        blocc.Check{Command: "false", WarnCodes: []int{1}, Severity: blocc.SeverityIgnore},
+			wantStatus:   blocc.StatusFailed,
+			wantSeverity: blocc.SeverityIgnore,
+		},
+		{name: "unmapped code", check: blocc.Check{Command: "false", SuccessCodes: []int{3}}, wantStatus: blocc.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := executeCheck(context.Background(), executor, tt.check)
+			if result.Status != tt.wantStatus || result.Severity != tt.wantSeverity || result.ExitCode != 1 {
+				t.Errorf("executeCheck() = %+v, want %s with severity %q", result, tt.wantStatus, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestExecuteCheckNormalization(t *testing.T) {
+	absPath, err := filepath.Abs("executor.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := blocctest.NewRunner().On("make", blocctest.Response{Stdout: absPath + "\n"})
+
+	tests := []struct {
+		name       string
+		check      blocc.Check
+		env        string
+		wantEnv    string
+		wantStdout string
+	}{
+		{
+			name:    "color disabled through environment",
+			check:   blocc.Check{Command: "make"},
+			env:     "NO_COLOR",
+			wantEnv: "1",
+		},
+		{
+			name:    "check environment overrides normalization environment",
+			check:   blocc.Check{Command: "make", Env: map[string]string{"TERM": "xterm"}},
+			env:     "TERM",
+			wantEnv: "xterm",
+		},
+		{
+			name:  "raw check keeps the inherited environment",
+			check: blocc.Check{Command: "make", Raw: true},
+			env:   "NO_COLOR",
+		},
+		{
+			name:       "paths relative to the repository root",
+			check:      blocc.Check{Command: "make", IncludeStdout: true},
+			wantStdout: "executor.go\n",
+		},
+		{
+			name:       "raw check keeps absolute paths",
+			check:      blocc.Check{Command: "make", IncludeStdout: true, Raw: true},
+			wantStdout: absPath + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+			result := executeCheck(context.Background(), executor, tt.check)
+
+			if result.Stdout != tt.wantStdout {
+				t.Errorf("executeCheck() stdout = %q, want %q", result.Stdout, tt.wantStdout)
+			}
+
+			calls := runner.Calls()
+			if value, _ := lookupEnv(calls[len(calls)-1].Env, tt.env); tt.env != "" && value != tt.wantEnv {
+				t.Errorf("executeCheck() %s = %q, want %q", tt.env, value, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func TestExecuteWithRetries(t *testing.T) {
+	failure, success := blocctest.Response{ExitCode: 1}, blocctest.Response{}
+
+	tests := []struct {
+		name         string
+		check        blocc.Check
+		wantStatus   blocc.Status
+		wantAttempts int
+		wantFlaky    bool
+		minDuration  time.Duration
+	}{
+		{name: "no retries", check: blocc.Check{Command: "./flaky.sh"}, wantStatus: blocc.StatusFailed},
+		{
+			name:         "passes after retrying with backoff",
+			check:        blocc.Check{Command: "./flaky.sh", Retries: 5, RetryDelay: 20 * time.Millisecond, RetryBackoff: 2},
+			wantStatus:   blocc.StatusPassed,
+			wantAttempts: 3,
+			wantFlaky:    true,
+			minDuration:  60 * time.Millisecond,
+		},
+		{
+			name:         "retries used up",
+			check:        blocc.Check{Command: "false", Retries: 2},
+			wantStatus:   blocc.StatusFailed,
+			wantAttempts: 3,
+		},
+		{
+			name:       "passing command is not retried",
+			check:      blocc.Check{Command: "true", Retries: 2},
+			wantStatus: blocc.StatusPassed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// flaky.sh fails until it has run three times.
+			runner := newRunner().On("./flaky.sh", failure, failure, success)
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+			result := executeCheck(context.Background(), executor, tt.check)
+
+			if result.Status != tt.wantStatus || result.Attempts != tt.wantAttempts || result.Flaky != tt.wantFlaky {
+				t.Errorf("executeWithRetries() status = %q, attempts = %d, flaky = %v, want %q, %d, %v",
+					result.Status, result.Attempts, result.Flaky, tt.wantStatus, tt.wantAttempts, tt.wantFlaky)
+			}
+
+			if time.Duration(result.Duration) < tt.minDuration {
+				t.Errorf("executeWithRetries() duration = %v, want at least %v", result.Duration, tt.minDuration)
+			}
+			if runs := len(runner.Calls()); runs != max(1, tt.wantAttempts) {
+				t.Errorf("executeWithRetries() ran the command %d times, want %d", runs, max(1, tt.wantAttempts))
+			}
+		})
+	}
+
+	runner := newRunner()
+	executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	result := executeCheck(ctx, executor, blocc.Check{Command: "false", Retries: 3, RetryDelay: time.Hour})
+	if result.Attempts > 1 || len(runner.Calls()) != 1 {
+		t.Errorf("executeWithRetries() retried after cancellation: %+v", result)
+	}
+}
+
+func TestExecuteChecksStream(t *testing.T) {
+	runner := newRunner().On("make build", blocctest.Response{Stdout: "building\ndone", Stderr: "broken\n", ExitCode: 1})
+
+	var stream bytes.Buffer
+	executor := blocc.NewExecutor(true, "", "", false).WithRunner(runner).WithStream(&stream).
+		WithStopPolicy(blocc.StopPolicy{})
+	results, err := executor.ExecuteChecksSequential([]blocc.Check{
+		{Name: "build", Command: "make build", IncludeStdout: true},
+		{Name: "greeting", Command: "echo hi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := stream.String()
+	for _, line := range []string{"build    | building\n", "build    | broken\n", "build    | done\n", "greeting | hi\n"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("stream = %q, want line %q", got, line)
+		}
+	}
+	if results[0].Stdout != "building\ndone" || strings.TrimSpace(results[0].Stderr) != "broken" {
+		t.Errorf("captured stdout %q, stderr %q, want output still captured", results[0].Stdout, results[0].Stderr)
+	}
+}
+
+func TestExecuteCheckLargeOutput(t *testing.T) {
+	// Like seq 1 1000000, which writes 6888896 bytes.
+	const outputBytes = 6888896
+	var output strings.Builder
+	for i := 1; i <= 1000000; i++ {
+		fmt.Fprintf(&output, "%d\n", i)
+	}
+	runner := blocctest.NewRunner().On("seq", blocctest.Response{Stdout: output.String()})
+
+	executor := blocc.NewExecutor(true, "", "", false).WithRunner(runner).WithCaptureLimit(64 << 10)
+	headAndTail := func(s string) bool {
+		return len(s) < 70<<10 && strings.HasPrefix(s, "1\n2\n") && strings.HasSuffix(s, "999999\n1000000\n") &&
+			strings.Contains(s, "... [truncated ")
+	}
+	tests := []struct {
+		name      string
+		filter    []string
+		want      func(string) bool
+		truncated bool
+	}{
+		{name: "head and tail", want: headAndTail, truncated: true},
+		{name: "grep reads spilled output", filter: []string{"grep:^500000$"}, want: eq("500000\n")},
+		{name: "tail reads spilled output", filter: []string{"tail:1", "extract:\\d+"}, want: eq("1000000\n")},
+		{name: "other filters see head and tail", filter: []string{"uniq", "head:1"}, want: eq("1\n"), truncated: true},
+		{name: "grep output within the capture limit", filter: []string{"grep:."}, want: headAndTail, truncated: true},
+		{
+			name:      "shell filter output within the capture limit",
+			filter:    []string{"sh:cat"},
+			want:      headAndTail,
+			truncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := blocc.NewPipeline("", tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check := blocc.Check{Command: "seq", IncludeStdout: true, StdoutPipeline: pipeline}
+
+			result := executeCheck(context.Background(), executor, check)
+			if result.Status != blocc.StatusPassed || result.StdoutBytes != outputBytes {
+				t.Fatalf("result = %s with %d stdout bytes, want passed with %d", result.Status, result.StdoutBytes, outputBytes)
+			}
+			if !tt.want(result.Stdout) {
+				t.Errorf("Stdout = %.100q ... %q", result.Stdout, result.Stdout[max(0, len(result.Stdout)-100):])
+			}
+			if result.Truncated != tt.truncated {
+				t.Errorf("Truncated = %v, want %v", result.Truncated, tt.truncated)
+			}
+		})
+	}
+}
+
+func eq(want string) func(string) bool {
+	return func(s string) bool { return s == want }
+}
+
+func TestExecuteCheckStdinAndPayloadEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := blocc.ReadHookPayload(strings.NewReader(
+		`{"session_id":"s1","cwd":"/repo","hook_event_name":"PostToolUse","tool_input":{"file_path":"/repo/a.go"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runner := blocctest.NewRunner().On("cat").On("echo", blocctest.Response{Stdout: "\n"})
+	executor := blocc.NewExecutor(true, "", "", false).WithRunner(runner).WithHookPayload(payload)
+	payloadEnv := map[string]string{
+		"BLOCC_SESSION_ID": "s1",
+		"BLOCC_EVENT":      "PostToolUse",
+		"BLOCC_CWD":        "/repo",
+		"BLOCC_FILE_PATH":  "/repo/a.go",
+	}
+	tests := []struct {
+		name       string
+		check      blocc.Check
+		wantStdin  string
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "payload", check: blocc.Check{Command: "cat", Stdin: blocc.StdinPayload}, wantStdin: string(payload.Raw)},
+		{name: "relative file", check: blocc.Check{Command: "cat", Stdin: "input.txt", Dir: dir}, wantStdin: "from file\n"},
+		{
+			name:       "missing file",
+			check:      blocc.Check{Command: "cat", Stdin: "missing.txt", Dir: dir},
+			wantStderr: "cannot read stdin",
+		},
+		{name: "no stdin", check: blocc.Check{Command: "cat"}},
+		{
+			name: "filter env",
+			check: blocc.Check{
+				Command:        "echo",
+				StdoutPipeline: blocc.Pipeline{blocc.ShellFilter{Command: "echo $BLOCC_FILE_PATH"}},
+			},
+			wantStdout: "/repo/a.go\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.IncludeStdout = true
+			calls := len(runner.Calls())
+			result := executeCheck(context.Background(), executor, tt.check)
+			if result.Stdout != tt.wantStdout {
+				t.Errorf("Stdout = %q, want %q", result.Stdout, tt.wantStdout)
+			}
+			if !strings.Contains(result.Stderr, tt.wantStderr) || (tt.wantStderr != "") != result.Failed() {
+				t.Errorf("result = %s, Stderr = %q, want stderr containing %q", result.Status, result.Stderr, tt.wantStderr)
+			}
+
+			ran := runner.Calls()[calls:]
+			if tt.wantStderr != "" {
+				if len(ran) != 0 {
+					t.Errorf("ran %+v, want the command not started", ran)
+				}
+				return
+			}
+			if ran[0].Stdin != tt.wantStdin {
+				t.Errorf("Stdin = %q, want %q", ran[0].Stdin, tt.wantStdin)
+			}
+			for key, want := range payloadEnv {
+				if value, _ := lookupEnv(ran[0].Env, key); value != want {
+					t.Errorf("%s = %q, want %q", key, value, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,564 @@
+package blocc
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type FilterErrorPolicy string
+
+const (
+	// FilterErrorFallback reports the unfiltered output and hides the failure.
+	FilterErrorFallback FilterErrorPolicy = "fallback"
+	// FilterErrorAnnotate reports the unfiltered output and records the failure in Result.FilterErrors.
+	FilterErrorAnnotate FilterErrorPolicy = "annotate"
+	// FilterErrorFail aborts the run with an error.
+	FilterErrorFail FilterErrorPolicy = "fail"
+)
+
+func ParseFilterErrorPolicy(s string) (FilterErrorPolicy, error) {
+	switch policy := FilterErrorPolicy(s); policy {
+	case FilterErrorFallback, FilterErrorAnnotate, FilterErrorFail:
+		return policy, nil
+	case "":
+		return FilterErrorAnnotate, nil
+	default:
+		return "", fmt.Errorf("unknown filter error policy %q", s)
+	}
+}
+
+// FilterError describes a filter stage that failed while processing a stream.
+type FilterError struct {
+	Stream   string `json:"stream"`
+	Filter   string `json:"filter,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (e *FilterError) Error() string {
+	msg := e.Message
+	if e.Filter != "" {
+		msg = fmt.Sprintf("filter %q: %s", e.Filter, msg)
+	}
+	if e.Stream != "" {
+		msg = e.Stream + " " + msg
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += ": " + stderr
+	}
+	return msg
+}
+
+// Filter transforms command output before it is reported.
+type Filter interface {
+	Apply(input string) (string, error)
+}
+
+// ReaderFilter is a Filter that can stream its input from r to w instead, so
+// that output too large to keep in memory can be filtered.
+type ReaderFilter interface {
+	Filter
+	ApplyReader(r io.Reader, w io.Writer) error
+}
+
+// applyString applies f to input and returns its whole output.
+func applyString(f ReaderFilter, input string) (string, error) {
+	var out strings.Builder
+	err := f.ApplyReader(strings.NewReader(input), &out)
+	return out.String(), err
+}
+
+// Pipeline chains filters, feeding the output of each stage into the next.
+type Pipeline []Filter
+
+func (p Pipeline) Apply(input string) (string, error) {
+	output := input
+	for _, f := range p {
+		var err error
+		output, err = f.Apply(output)
+		if err != nil {
+			return input, err
+		}
+	}
+	return output, nil
+}
+
+// WithEnv returns a copy of the pipeline whose shell filters run with env
+// added to the inherited environment.
+func (p Pipeline) WithEnv(env []string) Pipeline {
+	if len(env) == 0 {
+		return p
+	}
+	pipeline := slices.Clone(p)
+	for i, f := range pipeline {
+		if shell, ok := f.(ShellFilter); ok {
+			shell.Env = append(slices.Clone(shell.Env), env...)
+			pipeline[i] = shell
+		}
+	}
+	return pipeline
+}
+
+// NewPipeline builds a pipeline from an optional shell filter command followed
+// by built-in stage specs such as "grep:FAIL" or "head:20".
+func NewPipeline(shellFilter string, specs []string) (Pipeline, error) {
+	var pipeline Pipeline
+	if shellFilter != "" {
+		pipeline = append(pipeline, ShellFilter{Command: shellFilter})
+	}
+
+	for _, spec := range specs {
+		f, err := ParseFilter(spec)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, f)
+	}
+
+	return pipeline, nil
+}
+
+// ParseFilter parses a single stage spec of the form "name" or "name:argument".
+func ParseFilter(spec string) (Filter, error) {
+	name, arg, hasArg := strings.Cut(spec, ":")
+
+	switch name {
+	case "grep", "grep-v", "extract":
+		if !hasArg || arg == "" {
+			return nil, fmt.Errorf("filter %q requires a regular expression", name)
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q: %w", name, err)
+		}
+		switch name {
+		case "grep":
+			return GrepFilter{Pattern: re}, nil
+		case "grep-v":
+			return GrepFilter{Pattern: re, Invert: true}, nil
+		default:
+			return ExtractFilter{Pattern: re}, nil
+		}
+	case "head", "tail":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("filter %q requires a non-negative line count", name)
+		}
+		if name == "head" {
+			return HeadFilter{Lines: n}, nil
+		}
+		return TailFilter{Lines: n}, nil
+	case "uniq":
+		return UniqFilter{}, nil
+	case "sort":
+		return SortFilter{}, nil
+	case "strip-ansi":
+		return StripANSIFilter{}, nil
+	case "trim-blank":
+		return TrimBlankFilter{}, nil
+	case "json":
+		path, err := parseJSONPath(arg)
+		if err != nil {
+			return nil, err
+		}
+		return JSONFilter{Path: path}, nil
+	case "sh":
+		if !hasArg || arg == "" {
+			return nil, fmt.Errorf("filter %q requires a command", name)
+		}
+		return ShellFilter{Command: arg}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter %q", name)
+	}
+}
+
+func splitLines(input string) []string {
+	if input == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(input, "\n"), "\n")
+}
+
+// writeLines writes lines to w, each followed by a line break.
+func writeLines(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// readLines calls fn with every line read from r, without its line break,
+// until fn returns false.
+func readLines(r io.Reader, fn func(line string) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" && !fn(strings.TrimSuffix(line, "\n")) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// GrepFilter keeps the lines matching Pattern, or the ones not matching it when Invert is set.
+type GrepFilter struct {
+	Pattern *regexp.Regexp
+	Invert  bool
+}
+
+func (f GrepFilter) Apply(input string) (string, error) {
+	return applyString(f, input)
+}
+
+func (f GrepFilter) ApplyReader(r io.Reader, w io.Writer) error {
+	var werr error
+	err := readLines(r, func(line string) bool {
+		if f.Pattern.MatchString(line) != f.Invert {
+			werr = writeLines(w, []string{line})
+		}
+		return werr == nil
+	})
+	return cmp.Or(err, werr)
+}
+
+// ExtractFilter outputs the first capture group of every match, or the whole
+// match when the pattern has no groups.
+type ExtractFilter struct {
+	Pattern *regexp.Regexp
+}
+
+func (f ExtractFilter) Apply(input string) (string, error) {
+	return applyString(f, input)
+}
+
+func (f ExtractFilter) ApplyReader(r io.Reader, w io.Writer) error {
+	var werr error
+	err := readLines(r, func(line string) bool {
+		var extracted []string
+		for _, m := range f.Pattern.FindAllStringSubmatch(line, -1) {
+			if len(m) > 1 {
+				extracted = append(extracted, m[1])
+			} else {
+				extracted = append(extracted, m[0])
+			}
+		}
+		werr = writeLines(w, extracted)
+		return werr == nil
+	})
+	return cmp.Or(err, werr)
+}
+
+type HeadFilter struct {
+	Lines int
+}
+
+func (f HeadFilter) Apply(input string) (string, error) {
+	return applyString(f, input)
+}
+
+func (f HeadFilter) ApplyReader(r io.Reader, w io.Writer) error {
+	var lines []string
+	err := readLines(r, func(line string) bool {
+		if len(lines) == f.Lines {
+			return false
+		}
+		lines = append(lines, line)
+		return true
+	})
+	return cmp.Or(err, writeLines(w, lines))
+}
+
+type TailFilter struct {
+	Lines int
+}
+
+func (f TailFilter) Apply(input string) (string, error) {
+	return applyString(f, input)
+}
+
+func (f TailFilter) ApplyReader(r io.Reader, w io.Writer) error {
+	if f.Lines <= 0 {
+		return nil
+	}
+
+	// lines is a ring buffer once full; next is its oldest line.
+	var lines []string
+	next := 0
+	err := readLines(r, func(line string) bool {
+		if len(lines) < f.Lines {
+			lines = append(lines, line)
+		} else {
+			lines[next] = line
+			next = (next + 1) % f.Lines
+		}
+		return true
+	})
+	return cmp.Or(err, writeLines(w, append(lines[next:], lines[:next]...)))
+}
+
+// UniqFilter drops repeated lines, keeping the first occurrence.
+type UniqFilter struct{}
+
+func (UniqFilter) Apply(input string) (string, error) {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, line := range splitLines(input) {
+		if !seen[line] {
+			seen[line] = true
+			unique = append(unique, line)
+		}
+	}
+	return joinLines(unique), nil
+}
+
+type SortFilter struct{}
+
+func (SortFilter) Apply(input string) (string, error) {
+	lines := splitLines(input)
+	sort.Strings(lines)
+	return joinLines(lines), nil
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI removes ANSI CSI and OSC escape sequences from s.
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+type StripANSIFilter struct{}
+
+func (StripANSIFilter) Apply(input string) (string, error) {
+	return StripANSI(input), nil
+}
+
+// TrimBlankFilter removes empty and whitespace-only lines.
+type TrimBlankFilter struct{}
+
+func (TrimBlankFilter) Apply(input string) (string, error) {
+	var kept []string
+	for _, line := range splitLines(input) {
+		if strings.TrimSpace(line) != "" {
+			kept = append(kept, line)
+		}
+	}
+	return joinLines(kept), nil
+}
+
+// JSONFilter extracts values with a jq-style path such as ".results[].message".
+// Input may hold several JSON documents, e.g. JSON lines.
+type JSONFilter struct {
+	Path []jsonPathSegment
+}
+
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if path == "" || path == "." {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		return nil, fmt.Errorf("json path %q must start with '.'", path)
+	}
+
+	var segments []jsonPathSegment
+	rest := path
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q: missing ']'", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "" {
+				segments = append(segments, jsonPathSegment{iterate: true})
+				continue
+			}
+			if unquoted, err := strconv.Unquote(inner); err == nil {
+				segments = append(segments, jsonPathSegment{key: unquoted})
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("json path %q: invalid index %q", path, inner)
+			}
+			segments = append(segments, jsonPathSegment{index: n, isIndex: true})
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if key := rest[:end]; key != "" {
+				segments = append(segments, jsonPathSegment{key: key})
+			}
+			rest = rest[end:]
+		default:
+			return nil, fmt.Errorf("json path %q: unexpected %q", path, rest)
+		}
+	}
+
+	return segments, nil
+}
+
+func (f JSONFilter) Apply(input string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var out []string
+	for {
+		var doc any
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return input, fmt.Errorf("json filter: %w", err)
+		}
+
+		for _, v := range selectJSON(doc, f.Path) {
+			out = append(out, formatJSONValue(v))
+		}
+	}
+
+	return joinLines(out), nil
+}
+
+func selectJSON(v any, path []jsonPathSegment) []any {
+	if len(path) == 0 {
+		return []any{v}
+	}
+
+	seg, rest := path[0], path[1:]
+	switch {
+	case seg.iterate:
+		var out []any
+		switch x := v.(type) {
+		case []any:
+			for _, item := range x {
+				out = append(out, selectJSON(item, rest)...)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				out = append(out, selectJSON(x[k], rest)...)
+			}
+		}
+		return out
+	case seg.isIndex:
+		arr, ok := v.([]any)
+		if !ok {
+			return nil
+		}
+		i := seg.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil
+		}
+		return selectJSON(arr[i], rest)
+	default:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		child, ok := obj[seg.key]
+		if !ok {
+			return nil
+		}
+		return selectJSON(child, rest)
+	}
+}
+
+func formatJSONValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// ShellFilter pipes the input through `sh -c Command`.
+type ShellFilter struct {
+	Command string
+	// Env is added to the inherited environment.
+	Env []string
+}
+
+func (f ShellFilter) Apply(input string) (string, error) {
+	if input == "" {
+		return input, nil
+	}
+	output, err := applyString(f, input)
+	if err != nil {
+		return input, err
+	}
+	return output, nil
+}
+
+func (f ShellFilter) ApplyReader(r io.Reader, w io.Writer) error {
+	// #nosec G204 - This is a CLI tool designed to execute user-provided filter commands
+	cmd := exec.Command("sh", "-c", f.Command)
+	cmd.Stdin = r
+	if len(f.Env) > 0 {
+		cmd.Env = append(os.Environ(), f.Env...)
+	}
+
+	stderr := NewCapture(DefaultCaptureLimit, 0)
+	cmd.Stdout = w
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		filterErr := &FilterError{
+			Filter:   "sh:" + f.Command,
+			ExitCode: -1,
+			Stderr:   stderr.String(),
+			Message:  err.Error(),
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			filterErr.ExitCode = exitErr.ExitCode()
+		}
+		return filterErr
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package blocc
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "grep", spec: "grep:FAIL"},
+		{name: "grep with colon in pattern", spec: "grep:^\\S+:\\d+:"},
+		{name: "grep-v", spec: "grep-v:^ok"},
+		{name: "extract", spec: "extract:Unknown word \\((\\w+)\\)"},
+		{name: "head", spec: "head:10"},
+		{name: "tail", spec: "tail:0"},
+		{name: "uniq", spec: "uniq"},
+		{name: "sort", spec: "sort"},
+		{name: "strip-ansi", spec: "strip-ansi"},
+		{name: "trim-blank", spec: "trim-blank"},
+		{name: "json", spec: "json:.results[].message"},
+		{name: "sh", spec: "sh:sort | uniq"},
+		{name: "unknown stage", spec: "awk:{print}", wantErr: true},
+		{name: "grep without pattern", spec: "grep", wantErr: true},
+		{name: "invalid regexp", spec: "grep:(", wantErr: true},
+		{name: "head without count", spec: "head", wantErr: true},
+		{name: "negative tail", spec: "tail:-1", wantErr: true},
+		{name: "invalid json path", spec: "json:results", wantErr: true},
+		{name: "sh without command", spec: "sh:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseFilter(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPipelineApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []string
+		input string
+		want  string
+	}{
+		{
+			name:  "grep",
+			specs: []string{"grep:FAIL"},
+			input: "ok  pkg/a\nFAIL pkg/b\nok  pkg/c\nFAIL pkg/d\n",
+			want:  "FAIL pkg/b\nFAIL pkg/d\n",
+		},
+		{
+			name:  "grep-v",
+			specs: []string{"grep-v:^ok"},
+			input: "ok  pkg/a\nFAIL pkg/b\n",
+			want:  "FAIL pkg/b\n",
+		},
+		{
+			name:  "extract capture group",
+			specs: []string{"extract:Unknown word \\((\\w+)\\)"},
+			input: "a.go:1 - Unknown word (blocc)\nb.go:2 - Unknown word (kong) Unknown word (repr)\n",
+			want:  "blocc\nkong\nrepr\n",
+		},
+		{
+			name:  "extract whole match",
+			specs: []string{"extract:\\d+"},
+			input: "line 12 col 3\n",
+			want:  "12\n3\n",
+		},
+		{
+			name:  "head",
+			specs: []string{"head:2"},
+			input: "1\n2\n3\n",
+			want:  "1\n2\n",
+		},
+		{
+			name:  "tail",
+			specs: []string{"tail:2"},
+			input: "1\n2\n3\n",
+			want:  "2\n3\n",
+		},
+		{
+			name:  "uniq keeps first occurrence",
+			specs: []string{"uniq"},
+			input: "b\na\nb\nc\na\n",
+			want:  "b\na\nc\n",
+		},
+		{
+			name:  "sort",
+			specs: []string{"sort"},
+			input: "b\nc\na\n",
+			want:  "a\nb\nc\n",
+		},
+		{
+			name:  "strip-ansi",
+			specs: []string{"strip-ansi"},
+			input: "\x1b[31merror\x1b[0m: \x1b]8;;http://x\x07link\x1b]8;;\x07\n",
+			want:  "error: link\n",
+		},
+		{
+			name:  "trim-blank",
+			specs: []string{"trim-blank"},
+			input: "\na\n   \n\tb\n\n",
+			want:  "a\n\tb\n",
+		},
+		{
+			name:  "json path over documents",
+			specs: []string{"json:.results[].message"},
+			input: `{"results":[{"message":"one"},{"message":"two"}]}` + "\n" + `{"results":[{"message":"three"}]}`,
+			want:  "one\ntwo\nthree\n",
+		},
+		{
+			name:  "json index and non-string value",
+			specs: []string{"json:.items[1].count"},
+			input: `{"items":[{"count":1},{"count":{"n":2}}]}`,
+			want:  "{\"n\":2}\n",
+		},
+		{
+			name:  "json quoted key",
+			specs: []string{`json:.["file.name"]`},
+			input: `{"file.name":"main.go"}`,
+			want:  "main.go\n",
+		},
+		{
+			name:  "chained stages",
+			specs: []string{"strip-ansi", "extract:Unknown word \\((\\w+)\\)", "sort", "uniq", "head:2"},
+			input: "\x1b[1mUnknown word (zeta)\x1b[0m\nUnknown word (alpha)\nUnknown word (zeta)\nUnknown word (beta)\n",
+			want:  "alpha\nbeta\n",
+		},
+		{
+			name:  "empty output",
+			specs: []string{"grep:nothing"},
+			input: "a\nb\n",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := NewPipeline("", tt.specs)
+			if err != nil {
+				t.Fatalf("NewPipeline() error = %v", err)
+			}
+
+			got, err := pipeline.Apply(tt.input)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineApplyError(t *testing.T) {
+	pipeline, err := NewPipeline("", []string{"grep:.", "json:.a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "not json\n"
+	got, err := pipeline.Apply(input)
+	if err == nil {
+		t.Fatal("expected error for invalid JSON input")
+	}
+
+	if got != input {
+		t.Errorf("Apply() = %q, want original input %q", got, input)
+	}
+}
+
+func TestReaderFilters(t *testing.T) {
+	var input strings.Builder
+	for i := range 100000 {
+		fmt.Fprintf(&input, "line %d\n", i)
+	}
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "grep:^line 4242$", want: "line 4242\n"},
+		{spec: "grep-v:[0-8]", want: "line 9\nline 99\nline 999\nline 9999\nline 99999\n"},
+		{spec: "extract:^line (9999\\d)$", want: "99990\n99991\n99992\n99993\n99994\n99995\n99996\n99997\n99998\n99999\n"},
+		{spec: "head:2", want: "line 0\nline 1\n"},
+		{spec: "head:0", want: ""},
+		{spec: "tail:3", want: "line 99997\nline 99998\nline 99999\n"},
+		{spec: "tail:0", want: ""},
+	}
+
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rf, ok := f.(ReaderFilter)
+		if !ok {
+			t.Fatalf("filter %q is not a ReaderFilter", tt.spec)
+		}
+
+		var got strings.Builder
+		if err := rf.ApplyReader(strings.NewReader(input.String()), &got); err != nil || got.String() != tt.want {
+			t.Errorf("%s: ApplyReader() = %q, %v, want %q", tt.spec, got.String(), err, tt.want)
+		}
+		if got, _ := f.Apply(input.String()); got != tt.want {
+			t.Errorf("%s: Apply() = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+
+	var got strings.Builder
+	shell := ShellFilter{Command: "grep -c line"}
+	if err := shell.ApplyReader(strings.NewReader(input.String()), &got); err != nil || got.String() != "100000\n" {
+		t.Errorf("ShellFilter.ApplyReader() = %q, %v, want %q", got.String(), err, "100000\n")
+	}
+}
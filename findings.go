@@ -0,0 +1,76 @@
+package blocc
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Finding is a diagnostic with a source location parsed from command output.
+type Finding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+var (
+	// findingPattern matches "path:line[:col]: [level:] message" as printed by most compilers and linters.
+	findingPattern = regexp.MustCompile(
+		`^\s*([^\s:][^:]*?):(\d+)(?::(\d+))?:\s*(?:(?i:(error|warning|note|info))(?:\[[^\]]*\])?\s*:?\s*)?(.+)$`)
+	// arrowPattern matches rustc style locations ("--> src/main.rs:10:5") following a message line.
+	arrowPattern   = regexp.MustCompile(`^\s*--> ([^\s:][^:]*):(\d+)(?::(\d+))?\s*$`)
+	headingPattern = regexp.MustCompile(`^(?i:(error|warning|note|info))(?:\[[^\]]*\])?:\s*(.+)$`)
+)
+
+// ParseFindings extracts located diagnostics from compiler and linter output.
+func ParseFindings(output string) []Finding {
+	var findings []Finding
+	var heading *Finding
+
+	for _, line := range splitLines(output) {
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			heading = &Finding{Level: normalizeLevel(m[1]), Message: m[2]}
+			continue
+		}
+
+		if m := arrowPattern.FindStringSubmatch(line); m != nil {
+			if heading != nil {
+				f := *heading
+				f.File, f.Line, f.Column = m[1], atoi(m[2]), atoi(m[3])
+				findings = append(findings, f)
+				heading = nil
+			}
+			continue
+		}
+
+		if m := findingPattern.FindStringSubmatch(line); m != nil {
+			findings = append(findings, Finding{
+				File:    m[1],
+				Line:    atoi(m[2]),
+				Column:  atoi(m[3]),
+				Level:   normalizeLevel(m[4]),
+				Message: strings.TrimSpace(m[5]),
+			})
+		}
+	}
+
+	return findings
+}
+
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "warning":
+		return "warning"
+	case "note", "info":
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
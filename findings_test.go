@@ -0,0 +1,54 @@
+package blocc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Finding
+	}{
+		{
+			name:   "go vet style",
+			output: "# example\n./main.go:10:5: undefined: foo\n",
+			want:   []Finding{{File: "./main.go", Line: 10, Column: 5, Level: "error", Message: "undefined: foo"}},
+		},
+		{
+			name:   "gcc style with level",
+			output: "src/a.c:3:1: warning: unused variable 'x'\nsrc/a.c:4:2: note: declared here\n",
+			want: []Finding{
+				{File: "src/a.c", Line: 3, Column: 1, Level: "warning", Message: "unused variable 'x'"},
+				{File: "src/a.c", Line: 4, Column: 2, Level: "note", Message: "declared here"},
+			},
+		},
+		{
+			name:   "go test without column",
+			output: "--- FAIL: TestX (0.00s)\n    executor_test.go:42: unexpected result\nFAIL\n",
+			want:   []Finding{{File: "executor_test.go", Line: 42, Level: "error", Message: "unexpected result"}},
+		},
+		{
+			name:   "rustc style",
+			output: "warning: unused import\n --> src/lib.rs:1:5\n  |\nerror[E0308]: mismatched types\n  --> src/main.rs:4:9\n",
+			want: []Finding{
+				{File: "src/lib.rs", Line: 1, Column: 5, Level: "warning", Message: "unused import"},
+				{File: "src/main.rs", Line: 4, Column: 9, Level: "error", Message: "mismatched types"},
+			},
+		},
+		{
+			name:   "no findings",
+			output: "Build failed\nsee log for details\n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseFindings(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFindings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package blocc
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitIgnore matches paths against the rules of .gitignore files.
+type GitIgnore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	// base is the slash-separated directory of the .gitignore file, relative to
+	// the root, or "" for the root.
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// AddFile adds the rules of the .gitignore file in the slash-separated
+// directory dir, relative to the root. A missing file adds nothing.
+func (g *GitIgnore) AddFile(root, dir string) error {
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(dir), ".gitignore"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	base := dir
+	if base == "." {
+		base = ""
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		g.AddRule(base, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// AddRule adds a .gitignore line found in the directory base.
+func (g *GitIgnore) AddRule(base, line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	rule := ignoreRule{base: base}
+	if line, rule.negate = strings.CutPrefix(line, "!"); !rule.negate {
+		line = strings.TrimPrefix(line, `\`)
+	}
+	if line, rule.dirOnly = strings.CutSuffix(line, "/"); line == "" {
+		return
+	}
+	// A slash other than a trailing one anchors the pattern to base.
+	rule.anchored = strings.Contains(line, "/")
+	rule.pattern = strings.TrimPrefix(line, "/")
+
+	g.rules = append(g.rules, rule)
+}
+
+// Ignored reports whether the slash-separated path name, relative to the root,
+// is ignored. The last matching rule wins, as in git.
+func (g *GitIgnore) Ignored(name string, isDir bool) bool {
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		rel := name
+		if rule.base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(name, rule.base+"/"); !ok {
+				continue
+			}
+		}
+
+		var matched bool
+		if rule.anchored {
+			matched = matchGlob(rule.pattern, rel)
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,73 @@
+package blocc
+
+import (
+	"testing"
+)
+
+func TestGitIgnore(t *testing.T) {
+	var g GitIgnore
+	for _, line := range []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"/build",
+		"node_modules/",
+		"docs/**/*.tmp",
+		`\!bang`,
+	} {
+		g.AddRule("", line)
+	}
+	g.AddRule("web", "dist")
+	g.AddRule("web", "/cache.txt")
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "app.log", want: true},
+		{name: "logs/app.log", want: true},
+		{name: "keep.log", want: false},
+		{name: "build", isDir: true, want: true},
+		{name: "src/build", isDir: true, want: false},
+		{name: "node_modules", isDir: true, want: true},
+		{name: "pkg/node_modules", isDir: true, want: true},
+		{name: "node_modules", want: false},
+		{name: "docs/tmp.tmp", want: true},
+		{name: "docs/a/b/c.tmp", want: true},
+		{name: "src/c.tmp", want: false},
+		{name: "!bang", want: true},
+		{name: "web/dist", isDir: true, want: true},
+		{name: "web/src/dist", want: true},
+		{name: "dist", isDir: true, want: false},
+		{name: "web/cache.txt", want: true},
+		{name: "web/src/cache.txt", want: false},
+		{name: "main.go", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := g.Ignored(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %t) = %t, want %t", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestGitIgnoreAddFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitignore":     "*.out\n",
+		"sub/.gitignore": "local\n",
+	})
+
+	var g GitIgnore
+	for _, d := range []string{".", "sub", "missing"} {
+		if err := g.AddFile(dir, d); err != nil {
+			t.Fatalf("AddFile(%q) error = %v", d, err)
+		}
+	}
+
+	if !g.Ignored("a.out", false) || !g.Ignored("sub/local", false) || g.Ignored("local", false) {
+		t.Errorf("AddFile() rules = %+v", g.rules)
+	}
+}
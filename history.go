@@ -0,0 +1,304 @@
+package blocc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryEntry records one blocc run.
+type HistoryEntry struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Event     string    `json:"event,omitempty"`
+	Dir       string    `json:"cwd,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	Summary   Summary   `json:"summary"`
+	Results   []Result  `json:"results"`
+}
+
+// NewHistoryEntry returns an entry for a run started at startedAt.
+// payload may be nil when blocc does not run as a hook.
+func NewHistoryEntry(startedAt time.Time, payload *HookPayload, results []Result, summary Summary) HistoryEntry {
+	entry := HistoryEntry{
+		ID:        newRunID(startedAt),
+		StartedAt: startedAt,
+		Summary:   summary,
+		Results:   results,
+	}
+	entry.Dir, _ = os.Getwd()
+
+	if payload != nil {
+		entry.SessionID = payload.SessionID
+		entry.Event = payload.HookEventName
+	}
+
+	return entry
+}
+
+// Blocked reports whether the run blocked Claude.
+func (h HistoryEntry) Blocked() bool {
+	return len(FailedResults(h.Results)) > 0
+}
+
+func newRunID(t time.Time) string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return t.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Limits of the history file. Once it grows past DefaultHistoryMaxSize, Append
+// drops runs older than historyMaxAge and then the oldest runs until the rest
+// fits in half of the limit, so that it is not rewritten on every run.
+const (
+	DefaultHistoryMaxSize = 32 << 20
+	historyMaxAge         = 30 * 24 * time.Hour
+)
+
+// History is a run history stored as JSON lines, one entry per run.
+type History struct {
+	Path string
+	// MaxSize is the size in bytes the file may grow to, DefaultHistoryMaxSize when zero.
+	MaxSize int64
+}
+
+// StateDir returns $XDG_STATE_HOME/blocc, falling back to ~/.local/state/blocc
+// when XDG_STATE_HOME is unset.
+func StateDir() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	if stateDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine state directory: %w", err)
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateDir, "blocc"), nil
+}
+
+// DefaultHistoryPath returns history.jsonl in the state directory.
+func DefaultHistoryPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Append adds entry to the history file, creating it if needed, and drops old
+// runs once the file exceeds its maximum size.
+func (h History) Append(entry HistoryEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	f, err := os.OpenFile(h.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	if info, err := os.Stat(h.Path); err == nil && info.Size() > h.maxSize() {
+		return h.compact(time.Now().Add(-historyMaxAge))
+	}
+	return nil
+}
+
+func (h History) maxSize() int64 {
+	if h.MaxSize > 0 {
+		return h.MaxSize
+	}
+	return DefaultHistoryMaxSize
+}
+
+// compact rewrites the history without the runs started before cutoff and
+// with only the newest runs that fit in half of the maximum size. The latest
+// run is always kept.
+func (h History) compact(cutoff time.Time) error {
+	entries, err := h.Load()
+	if err != nil {
+		return err
+	}
+
+	var lines [][]byte
+	size := int64(0)
+	for i := len(entries) - 1; i >= 0; i-- {
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		size += int64(len(line)) + 1
+		if len(lines) > 0 && (entries[i].StartedAt.Before(cutoff) || size > h.maxSize()/2) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	slices.Reverse(lines)
+
+	// Write a new file and rename it over the old one, so that a failure
+	// cannot lose the history.
+	tmp, err := os.CreateTemp(filepath.Dir(h.Path), ".history-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("failed to compact history file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(append(bytes.Join(lines, []byte("\n")), '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), h.Path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compact history file: %w", err)
+	}
+	return nil
+}
+
+// Load returns all entries, oldest first. A missing history file has no entries.
+func (h History) Load() ([]HistoryEntry, error) {
+	f, err := os.Open(h.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid history entry: %w", h.Path, lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// FindHistoryEntry returns the entry whose ID starts with id, or the latest
+// entry for "last".
+func FindHistoryEntry(entries []HistoryEntry, id string) (HistoryEntry, error) {
+	if id == "last" {
+		if len(entries) == 0 {
+			return HistoryEntry{}, errors.New("history is empty")
+		}
+		return entries[len(entries)-1], nil
+	}
+
+	var matches []HistoryEntry
+	for _, e := range entries {
+		if strings.HasPrefix(e.ID, id) {
+			matches = append(matches, e)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return HistoryEntry{}, fmt.Errorf("no run matches %q", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return HistoryEntry{}, fmt.Errorf("%d runs match %q, use a longer ID", len(matches), id)
+	}
+}
+
+// CheckStats aggregates the history of one check.
+type CheckStats struct {
+	Check    string
+	Runs     int
+	Failures int
+	Flaky    int
+	Duration time.Duration
+}
+
+// FailureRate returns the share of runs in which the check blocked.
+func (s CheckStats) FailureRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Failures) / float64(s.Runs)
+}
+
+// AverageDuration returns the mean duration of the check's runs.
+func (s CheckStats) AverageDuration() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.Duration / time.Duration(s.Runs)
+}
+
+// HistoryStats computes per-check statistics, most failing checks first. Flaky
+// passes count as passing runs.
+// Skipped, cancelled and cached results do not count as runs.
+func HistoryStats(entries []HistoryEntry) []CheckStats {
+	index := make(map[string]int)
+	var stats []CheckStats
+
+	for _, e := range entries {
+		for _, r := range e.Results {
+			if r.Status == StatusSkipped || r.Status == StatusCancelled || r.Status == StatusCached {
+				continue
+			}
+
+			title := resultTitle(r)
+			i, ok := index[title]
+			if !ok {
+				i = len(stats)
+				index[title] = i
+				stats = append(stats, CheckStats{Check: title})
+			}
+
+			stats[i].Runs++
+			stats[i].Duration += time.Duration(r.Duration)
+			if r.Blocking() {
+				stats[i].Failures++
+			}
+			if r.Flaky {
+				stats[i].Flaky++
+			}
+		}
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Failures != stats[j].Failures {
+			return stats[i].Failures > stats[j].Failures
+		}
+		return stats[i].FailureRate() > stats[j].FailureRate()
+	})
+
+	return stats
+}
@@ -0,0 +1,192 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryAppendLoad(t *testing.T) {
+	h := History{Path: filepath.Join(t.TempDir(), "state", "history.jsonl")}
+
+	entries, err := h.Load()
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("Load() on missing file = %v, %v", entries, err)
+	}
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	results := []Result{
+		{Command: "true", Status: StatusPassed, Duration: Duration(time.Second)},
+		{Command: "false", Status: StatusFailed, ExitCode: 1, Stderr: "boom\n"},
+	}
+	payload := &HookPayload{SessionID: "s1", HookEventName: "Stop"}
+	first := NewHistoryEntry(start, payload, results, NewSummary(results, time.Second))
+	second := NewHistoryEntry(start.Add(time.Minute), nil, results[:1], NewSummary(results[:1], time.Second))
+
+	for _, e := range []HistoryEntry{first, second} {
+		if err := h.Append(e); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err = h.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != first.ID || entries[0].SessionID != "s1" || entries[0].Event != "Stop" {
+		t.Fatalf("Load() = %+v", entries)
+	}
+
+	if !entries[0].Blocked() || entries[1].Blocked() {
+		t.Errorf("Blocked() = %v, %v, want true, false", entries[0].Blocked(), entries[1].Blocked())
+	}
+
+	if entries[0].Results[1].Stderr != "boom\n" || time.Duration(entries[0].Results[0].Duration) != time.Second {
+		t.Errorf("Load() results = %+v", entries[0].Results)
+	}
+
+	if !strings.HasPrefix(first.ID, "20250102-030405-") {
+		t.Errorf("NewHistoryEntry() ID = %q", first.ID)
+	}
+
+	if err := os.WriteFile(h.Path, []byte("{broken\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Load(); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("Load() error = %v, want line number", err)
+	}
+}
+
+func TestHistoryAppendDropsOldRuns(t *testing.T) {
+	h := History{Path: filepath.Join(t.TempDir(), "history.jsonl"), MaxSize: 4 << 10}
+	results := []Result{{Command: "make test", Status: StatusFailed, ExitCode: 1, Stderr: strings.Repeat("x", 200)}}
+
+	old := NewHistoryEntry(time.Now().Add(-60*24*time.Hour), nil, results, NewSummary(results, time.Second))
+	if err := h.Append(old); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := range 50 {
+		entry := NewHistoryEntry(time.Now().Add(time.Duration(i)*time.Second), nil, results, NewSummary(results, time.Second))
+		if err := h.Append(entry); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		ids = append(ids, entry.ID)
+
+		if info, err := os.Stat(h.Path); err != nil || info.Size() > h.MaxSize {
+			t.Fatalf("history file size = %d, %v, want at most %d", info.Size(), err, h.MaxSize)
+		}
+	}
+
+	entries, err := h.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, e := range entries {
+		kept = append(kept, e.ID)
+	}
+	if len(kept) == 0 || !slices.Equal(kept, ids[len(ids)-len(kept):]) {
+		t.Errorf("Load() = %q, want the newest of %q", kept, ids)
+	}
+
+	// Runs older than the maximum age go even when they fit.
+	fresh := NewHistoryEntry(time.Now(), nil, results, NewSummary(results, time.Second))
+	aged := History{Path: filepath.Join(t.TempDir(), "history.jsonl")}
+	for _, e := range []HistoryEntry{old, fresh} {
+		if err := aged.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := aged.compact(time.Now().Add(-historyMaxAge)); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := aged.Load(); err != nil || len(entries) != 1 || entries[0].ID != fresh.ID {
+		t.Errorf("Load() after compact = %d entries, %v, want only the recent run", len(entries), err)
+	}
+
+	// A run larger than the limit still replaces the older ones.
+	large := []Result{{Command: "make test", Status: StatusFailed, ExitCode: 1, Stderr: strings.Repeat("x", 8<<10)}}
+	last := NewHistoryEntry(time.Now(), nil, large, NewSummary(large, time.Second))
+	if err := h.Append(last); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := h.Load(); err != nil || len(entries) != 1 || entries[0].ID != last.ID {
+		t.Errorf("Load() = %d entries, %v, want only the latest run", len(entries), err)
+	}
+}
+
+func TestFindHistoryEntry(t *testing.T) {
+	entries := []HistoryEntry{{ID: "20250102-030405-aaaaaa"}, {ID: "20250102-030405-bbbbbb"}}
+
+	tests := []struct {
+		id      string
+		want    string
+		wantErr string
+	}{
+		{id: "last", want: "20250102-030405-bbbbbb"},
+		{id: "20250102-030405-a", want: "20250102-030405-aaaaaa"},
+		{id: "2025", wantErr: "2 runs match"},
+		{id: "1999", wantErr: "no run matches"},
+	}
+
+	for _, tt := range tests {
+		got, err := FindHistoryEntry(entries, tt.id)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FindHistoryEntry(%q) error = %v, want %q", tt.id, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got.ID != tt.want {
+			t.Errorf("FindHistoryEntry(%q) = %q, %v, want %q", tt.id, got.ID, err, tt.want)
+		}
+	}
+
+	if _, err := FindHistoryEntry(nil, "last"); err == nil {
+		t.Error("FindHistoryEntry() expected error for empty history")
+	}
+}
+
+func TestHistoryStats(t *testing.T) {
+	entries := []HistoryEntry{
+		{Results: []Result{
+			{Command: "lint", Status: StatusPassed, Duration: Duration(time.Second)},
+			{Name: "test", Command: "go test", Status: StatusFailed, Duration: Duration(3 * time.Second)},
+		}},
+		{Results: []Result{
+			{Command: "lint", Status: StatusFailed, Severity: SeverityWarn, Duration: Duration(time.Second)},
+			{Name: "test", Command: "go test", Status: StatusTimedOut, Duration: Duration(5 * time.Second)},
+		}},
+		{Results: []Result{
+			{Command: "lint", Status: StatusSkipped},
+		}},
+	}
+
+	stats := HistoryStats(entries)
+	if len(stats) != 2 {
+		t.Fatalf("HistoryStats() = %+v", stats)
+	}
+
+	test, lint := stats[0], stats[1]
+	if test.Check != "test" || test.Runs != 2 || test.Failures != 2 || test.AverageDuration() != 4*time.Second {
+		t.Errorf("test stats = %+v", test)
+	}
+
+	if lint.Check != "lint" || lint.Runs != 2 || lint.Failures != 0 || lint.FailureRate() != 0 {
+		t.Errorf("lint stats = %+v", lint)
+	}
+}
+
+func TestDefaultHistoryPath(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+
+	path, err := DefaultHistoryPath()
+	if err != nil || path != "/tmp/state/blocc/history.jsonl" {
+		t.Errorf("DefaultHistoryPath() = %q, %v", path, err)
+	}
+}
@@ -13,6 +13,8 @@ import (
 type Hook struct {
 	Type    string `json:"type"`
 	Command string `json:"command"`
+	// Timeout is in seconds.
+	Timeout int `json:"timeout,omitempty"`
 }
 
 type HookItem struct {
@@ -215,9 +215,7 @@ func TestInitSettings_ValidJSON(t *testing.T) {
 
 func TestInitSettings_WithStdout(t *testing.T) {
 	tempDir := t.TempDir()
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatal(err)
-	}
+	t.Chdir(tempDir)
 
 	// Test with stdout enabled
 	err := InitSettings([]string{"echo test"}, "Test message", true, "", "", false)
@@ -435,9 +433,7 @@ func TestInitSettings_WithFilters(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create temporary directory for test
 			tmpDir := t.TempDir()
-			if err := os.Chdir(tmpDir); err != nil {
-				t.Fatal(err)
-			}
+			t.Chdir(tmpDir)
 
 			// Run InitSettings
 			err := InitSettings(tt.commands, tt.message, tt.includeStdout, tt.stdoutFilter, tt.stderrFilter, false)
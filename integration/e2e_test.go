@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"os"
@@ -8,15 +9,27 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 type ErrorOutput struct {
 	Message string `json:"message"`
 	Results []struct {
+		Name     string `json:"name,omitempty"`
 		Command  string `json:"command"`
 		ExitCode int    `json:"exitCode"`
 		Stderr   string `json:"stderr"`
 		Stdout   string `json:"stdout,omitempty"`
+
+		FilterErrors []struct {
+			Stream   string `json:"stream"`
+			ExitCode int    `json:"exitCode"`
+			Stderr   string `json:"stderr"`
+		} `json:"filterErrors,omitempty"`
+
+		Truncated           bool `json:"truncated,omitempty"`
+		OriginalStdoutBytes int  `json:"originalStdoutBytes,omitempty"`
+		StdoutBytes         int  `json:"stdoutBytes,omitempty"`
 	} `json:"results"`
 }
 
@@ -193,9 +206,22 @@ exit 1`
 	})
 }
 
-func TestBlocc_CustomMessage(t *testing.T) {
-	customMsg := "Custom error occurred"
-	cmd := exec.Command("../blocc", "--message", customMsg, "false")
+func TestBlocc_FilterPipes(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "spell.sh")
+	script := `#!/bin/sh
+echo "a.go:1 - Unknown word (zeta)"
+echo "b.go:2 - Unknown word (alpha)"
+echo "c.go:3 - Unknown word (zeta)"
+exit 1`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", "--stdout", "--no-stderr",
+		"--stdout-pipe", `extract:Unknown word \((\w+)\)`,
+		"--stdout-pipe", "sort",
+		"--stdout-pipe", "uniq",
+		scriptPath)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
@@ -206,186 +232,1310 @@ func TestBlocc_CustomMessage(t *testing.T) {
 		t.Fatalf("Failed to unmarshal stderr: %v", err)
 	}
 
-	if errOut.Message != customMsg {
-		t.Errorf("Expected message %q, got %q", customMsg, errOut.Message)
+	if len(errOut.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(errOut.Results))
+	}
+
+	if got := errOut.Results[0].Stdout; got != "alpha\nzeta\n" {
+		t.Errorf("Expected filtered stdout %q, got %q", "alpha\nzeta\n", got)
 	}
 }
 
-func TestBlocc_Version(t *testing.T) {
-	cmd := exec.Command("../blocc", "--version")
-	output, err := cmd.Output()
+func TestBlocc_InvalidFilterPipe(t *testing.T) {
+	cmd := exec.Command("../blocc", "--stdout-pipe", "awk:{print}", "echo hello")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
-	if err != nil {
-		t.Fatalf("Version command failed: %v", err)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Errorf("Expected exit code 1, got %v", err)
 	}
 
-	if !strings.Contains(string(output), "blocc version") {
-		t.Errorf("Expected version output to contain 'blocc version', got %q", string(output))
+	if !strings.Contains(stderr.String(), "unknown filter") {
+		t.Errorf("Expected unknown filter error, got %q", stderr.String())
 	}
 }
 
-// validateInitResult validates the generated settings file structure
-func validateInitResult(t *testing.T, tmpDir, expectedCommand string) {
-	settingsPath := filepath.Join(tmpDir, ".claude", "settings.local.json")
-	if _, statErr := os.Stat(settingsPath); os.IsNotExist(statErr) {
-		t.Errorf("settings.local.json was not created at %s", settingsPath)
-		return
+func TestBlocc_FilterErrors(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "fail.sh")
+	script := `#!/bin/sh
+echo "stdout line"
+exit 1`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
 	}
 
-	content, err := os.ReadFile(settingsPath)
-	if err != nil {
-		t.Fatalf("Failed to read settings file: %v", err)
+	t.Run("annotate by default", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--stdout", "-o", "nonexistentcommand123", scriptPath)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		_ = cmd.Run() // We expect this to fail
+
+		var errOut ErrorOutput
+		if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+			t.Fatalf("Failed to unmarshal stderr: %v", err)
+		}
+
+		result := errOut.Results[0]
+		if result.Stdout != "stdout line\n" {
+			t.Errorf("Expected unfiltered stdout, got %q", result.Stdout)
+		}
+
+		if len(result.FilterErrors) != 1 {
+			t.Fatalf("Expected 1 filter error, got %d", len(result.FilterErrors))
+		}
+
+		fe := result.FilterErrors[0]
+		if fe.Stream != "stdout" || fe.ExitCode != 127 || !strings.Contains(fe.Stderr, "nonexistentcommand123") {
+			t.Errorf("Unexpected filter error: %+v", fe)
+		}
+	})
+
+	t.Run("fail policy exits with 1", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--stdout", "-o", "nonexistentcommand123", "--filter-error", "fail", scriptPath)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Errorf("Expected exit code 1, got %v", err)
+		}
+
+		if !strings.Contains(stderr.String(), "nonexistentcommand123") {
+			t.Errorf("Expected filter error message, got %q", stderr.String())
+		}
+	})
+}
+
+func TestBlocc_Doctor(t *testing.T) {
+	t.Run("valid filters", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "doctor", "-o", "sort | uniq", "--stderr-pipe", "head:5", "echo hello")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Expected doctor to succeed, got %v: %s", err, output)
+		}
+
+		if strings.Contains(string(output), "error") {
+			t.Errorf("Expected no errors, got %q", string(output))
+		}
+	})
+
+	t.Run("broken filters", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "doctor", "-o", "nonexistentcommand123", "--stderr-pipe", "awk:{print}")
+		output, err := cmd.Output()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Errorf("Expected exit code 1, got %v", err)
+		}
+
+		if !strings.Contains(string(output), "2 problem(s) found") {
+			t.Errorf("Expected 2 problems, got %q", string(output))
+		}
+	})
+}
+
+func TestBlocc_PerCommandChecks(t *testing.T) {
+	dir := t.TempDir()
+
+	scriptPath := filepath.Join(dir, "check.sh")
+	script := `#!/bin/sh
+echo "cwd=$(pwd) mode=$MODE"
+echo "failure detail" >&2
+exit 1`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(content, &result); err != nil {
-		t.Fatalf("Generated file is not valid JSON: %v", err)
+	configPath := filepath.Join(dir, "blocc.json")
+	config := `{
+  "checks": [
+    {"name": "from-config", "command": "` + scriptPath + `", "stdout": true, "noStderr": true,
+     "cwd": "` + dir + `", "env": {"MODE": "config"}},
+    {"name": "optional", "command": "false", "allowFailure": true}
+  ]
+}`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
 	}
 
-	hooks, ok := result["hooks"].(map[string]interface{})
-	if !ok {
-		t.Error("Missing 'hooks' field")
-		return
+	cmd := exec.Command("../blocc", "--config", configPath,
+		"--check", "name=from-flag,cmd="+scriptPath+",stderr-pipe=grep:detail,env=MODE=flag",
+		"false")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Errorf("Expected exit code 2, got %v", err)
 	}
 
-	stopHooks, ok := hooks["Stop"].([]interface{})
-	if !ok {
-		t.Error("Missing 'Stop' field")
-		return
+	var errOut ErrorOutput
+	if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+		t.Fatalf("Failed to unmarshal stderr: %v", err)
 	}
 
-	if len(stopHooks) != 1 {
-		t.Errorf("Expected 1 Stop entry, got %d", len(stopHooks))
-		return
+	if len(errOut.Results) != 3 {
+		t.Fatalf("Expected 3 failed checks, got %d: %+v", len(errOut.Results), errOut.Results)
 	}
 
-	entry := stopHooks[0].(map[string]interface{})
-	matcher, ok := entry["matcher"].(string)
-	if !ok || matcher != "" {
-		t.Errorf("Expected empty matcher, got %q", matcher)
+	fromConfig, fromFlag, positional := errOut.Results[0], errOut.Results[1], errOut.Results[2]
+
+	if fromConfig.Name != "from-config" || fromConfig.Stdout != "cwd="+dir+" mode=config\n" || fromConfig.Stderr != "" {
+		t.Errorf("Unexpected config check result: %+v", fromConfig)
 	}
 
-	entryHooks, ok := entry["hooks"].([]interface{})
-	if !ok || len(entryHooks) != 1 {
-		t.Errorf("Expected 1 hook entry, got %d", len(entryHooks))
-		return
+	if fromFlag.Name != "from-flag" || fromFlag.Stdout != "" || fromFlag.Stderr != "failure detail\n" {
+		t.Errorf("Unexpected flag check result: %+v", fromFlag)
 	}
 
-	hook := entryHooks[0].(map[string]interface{})
-	hookType, ok := hook["type"].(string)
-	if !ok || hookType != "command" {
-		t.Errorf("Expected hook type 'command', got %q", hookType)
+	if positional.Command != "false" || positional.Name != "" {
+		t.Errorf("Unexpected positional check result: %+v", positional)
 	}
+}
 
-	command, ok := hook["command"].(string)
-	if !ok || command != expectedCommand {
-		t.Errorf("Expected command %q, got %q", expectedCommand, command)
+func TestBlocc_MaxOutput(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "noisy.sh")
+	script := `#!/bin/sh
+i=0
+while [ $i -lt 2000 ]; do
+  echo "noise line $i"
+  i=$((i+1))
+done
+echo "main.go:1:1: error: something broke"
+exit 1`
+	if err := os.WriteFile(scriptPath, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", "--stdout", "--max-output", "1kb", "--truncate", "errors", scriptPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	_ = cmd.Run() // We expect this to fail
+
+	var errOut ErrorOutput
+	if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+		t.Fatalf("Failed to unmarshal stderr: %v", err)
+	}
+
+	if len(errOut.Results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(errOut.Results))
+	}
+
+	result := errOut.Results[0]
+	if !result.Truncated {
+		t.Error("Expected result to be marked as truncated")
+	}
+
+	if result.OriginalStdoutBytes <= 1024 {
+		t.Errorf("Expected original stdout size to be recorded, got %d", result.OriginalStdoutBytes)
+	}
+
+	if !strings.Contains(result.Stdout, "error: something broke") {
+		t.Errorf("Expected error line to be kept, got %q", result.Stdout)
+	}
+
+	if strings.Contains(result.Stdout, "noise line 0\n") {
+		t.Errorf("Expected noise to be dropped, got %q", result.Stdout)
 	}
 }
 
-func TestBlocc_Init(t *testing.T) {
+func TestBlocc_CustomMessage(t *testing.T) {
+	customMsg := "Custom error occurred"
+	cmd := exec.Command("../blocc", "--message", customMsg, "false")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	_ = cmd.Run() // We expect this to fail
+
+	var errOut ErrorOutput
+	if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+		t.Fatalf("Failed to unmarshal stderr: %v", err)
+	}
+
+	if errOut.Message != customMsg {
+		t.Errorf("Expected message %q, got %q", customMsg, errOut.Message)
+	}
+}
+
+func TestBlocc_Format(t *testing.T) {
 	tests := []struct {
-		name            string
-		args            []string
-		expectedCommand string
+		format string
+		want   string
+		// prefix compares only the start of the output, which is followed by timing metadata.
+		prefix bool
 	}{
+		{format: "markdown", want: "# 1 command(s) failed\n\n## `false` (exit code 1)\n"},
+		{format: "text", want: "1 command(s) failed\n\n--- FAIL: false (exit code 1)\n"},
 		{
-			name:            "init with custom command",
-			args:            []string{"--init", "npm run lint"},
-			expectedCommand: `blocc 'npm run lint'`,
-		},
-		{
-			name:            "init with custom message and commands",
-			args:            []string{"--init", "--message", "Custom error", "npm run lint", "npm run test"},
-			expectedCommand: `blocc --message "Custom error" 'npm run lint' 'npm run test'`,
+			format: "compact",
+			want:   `{"message":"1 command(s) failed","results":[{"command":"false","exitCode":1,"status":"failed",`,
+			prefix: true,
 		},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tmpDir := t.TempDir()
-
-			bloccPath, err := filepath.Abs("../blocc")
-			if err != nil {
-				t.Fatal(err)
-			}
-
-			cmd := exec.Command(bloccPath, tt.args...)
-			cmd.Dir = tmpDir
-			var stdout, stderr bytes.Buffer
-			cmd.Stdout = &stdout
+		t.Run(tt.format, func(t *testing.T) {
+			cmd := exec.Command("../blocc", "--format", tt.format, "false")
+			var stderr bytes.Buffer
 			cmd.Stderr = &stderr
 
-			err = cmd.Run()
-			if err != nil {
-				t.Fatalf("Init command failed: %v, stderr: %s", err, stderr.String())
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Errorf("Expected exit code 2, got %v", err)
 			}
 
-			outputStr := stdout.String()
-			if !strings.Contains(outputStr, "Successfully created settings.local.json") {
-				t.Errorf("Expected success message, got %q", outputStr)
+			got := stderr.String()
+			if tt.prefix {
+				if !strings.HasPrefix(got, tt.want) || strings.Count(got, "\n") != 1 {
+					t.Errorf("Expected single line starting with %q, got %q", tt.want, got)
+				}
+			} else if got != tt.want {
+				t.Errorf("Expected output %q, got %q", tt.want, got)
 			}
-
-			validateInitResult(t, tmpDir, tt.expectedCommand)
 		})
 	}
 }
 
-func TestBlocc_InitFileAlreadyExists(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestBlocc_ResultMetadata(t *testing.T) {
+	cmd := exec.Command("../blocc", "true", "false")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
-	// Create the .claude directory and settings file first
-	claudeDir := filepath.Join(tmpDir, ".claude")
-	if err := os.MkdirAll(claudeDir, 0755); err != nil {
-		t.Fatal(err)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Errorf("Expected exit code 2, got %v", err)
 	}
 
-	settingsPath := filepath.Join(claudeDir, "settings.local.json")
-	if err := os.WriteFile(settingsPath, []byte("{}"), 0600); err != nil {
-		t.Fatal(err)
+	var output struct {
+		Results []struct {
+			Status     string    `json:"status"`
+			StartedAt  time.Time `json:"startedAt"`
+			Duration   string    `json:"duration"`
+			Cwd        string    `json:"cwd"`
+			Executable string    `json:"executable"`
+		} `json:"results"`
+		Summary struct {
+			Total    int    `json:"total"`
+			Passed   int    `json:"passed"`
+			Failed   int    `json:"failed"`
+			Duration string `json:"duration"`
+		} `json:"summary"`
 	}
-
-	bloccPath, err := filepath.Abs("../blocc")
-	if err != nil {
-		t.Fatal(err)
+	if err := json.Unmarshal(stderr.Bytes(), &output); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\nOutput: %s", err, stderr.String())
 	}
 
-	// Try to init again - should fail
-	cmd := exec.Command(bloccPath, "--init", "echo test")
-	cmd.Dir = tmpDir
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	if len(output.Results) != 1 {
+		t.Fatalf("Expected 1 failed result, got %d", len(output.Results))
+	}
 
-	err = cmd.Run()
-	if err == nil {
-		t.Error("Expected command to fail when file already exists")
-		return
+	r := output.Results[0]
+	if r.Status != "failed" || r.StartedAt.IsZero() || r.Duration == "" || r.Cwd == "" || !filepath.IsAbs(r.Executable) {
+		t.Errorf("Expected result metadata, got %+v", r)
 	}
 
-	stderrStr := stderr.String()
-	if !strings.Contains(stderrStr, "already exists") {
-		t.Errorf("Expected error message to contain 'already exists', got %q", stderrStr)
+	summary := output.Summary
+	if summary.Total != 2 || summary.Passed != 1 || summary.Failed != 1 || summary.Duration == "" {
+		t.Errorf("Expected summary of 2 checks, got %+v", summary)
 	}
 }
 
-func TestBlocc_InitPathDisplay(t *testing.T) {
-	// Test that path display shows ~ when possible
+func TestBlocc_ReportFile(t *testing.T) {
 	tmpDir := t.TempDir()
 
-	bloccPath, err := filepath.Abs("../blocc")
-	if err != nil {
-		t.Fatal(err)
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{
+			file: "report.xml",
+			want: []string{
+				"<?xml",
+				`<testcase name="echo hello"`,
+				`<testcase name="false"`,
+				`<failure message="false exited with code 1"`,
+			},
+		},
+		{file: "report.sarif", want: []string{`"version": "2.1.0"`, `"name": "false"`}},
 	}
 
-	cmd := exec.Command(bloccPath, "--init", "echo test")
-	cmd.Dir = tmpDir
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.file)
+			cmd := exec.Command("../blocc", "--report-file", path, "echo hello", "false")
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
 
-	err = cmd.Run()
-	if err != nil {
-		t.Fatalf("Init command failed: %v", err)
-	}
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Errorf("Expected exit code 2, got %v", err)
+			}
+
+			// The hook output is unchanged.
+			if !strings.Contains(stderr.String(), `"command": "false"`) {
+				t.Errorf("Expected JSON hook output, got %q", stderr.String())
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read report: %v", err)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Expected report to contain %q, got:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
+
+func TestBlocc_SuccessOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout []string
+	}{
+		{name: "silent by default", args: []string{"true"}},
+		{
+			name:       "summary",
+			args:       []string{"--on-success", "summary", "true", "echo hi"},
+			wantStdout: []string{"blocc: 2 passed in "},
+		},
+		{
+			name:       "system message",
+			args:       []string{"--on-success", "system-message", "true"},
+			wantStdout: []string{`{"systemMessage":"blocc: 1 passed in `},
+		},
+		{
+			name:       "verbose",
+			args:       []string{"--verbose", "true", "echo hi"},
+			wantStdout: []string{"PASS    true (", "PASS    echo hi ("},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", tt.args...)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("Expected exit code 0, got %v: %s", err, stderr.String())
+			}
+
+			if len(tt.wantStdout) == 0 && stdout.Len() > 0 {
+				t.Errorf("Expected no stdout, got %q", stdout.String())
+			}
+
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected stdout to contain %q, got %q", want, stdout.String())
+				}
+			}
+		})
+	}
+
+	t.Run("verbose on failure", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--verbose", "true", "false")
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Errorf("Expected exit code 2, got %v", err)
+		}
+
+		got := stdout.String()
+		if !strings.Contains(got, "PASS    true (") || !strings.Contains(got, "FAIL    false (exit code 1, ") {
+			t.Errorf("Expected status list on stdout, got %q", got)
+		}
+	})
+}
+
+func TestBlocc_History(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "history.jsonl")
+
+	run := func(stdin string, args ...string) (string, error) {
+		cmd := exec.Command("../blocc", append([]string{"--history-file", historyFile}, args...)...)
+		cmd.Stdin = strings.NewReader(stdin)
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		err := cmd.Run()
+		return stdout.String(), err
+	}
+
+	payload := `{"session_id":"session-1","hook_event_name":"Stop"}`
+	if _, err := run(payload, "--record", "--payload", "true", "false"); err == nil {
+		t.Fatal("Expected failing run to exit with code 2")
+	}
+
+	// Without --payload, stdin is not read, so input that never ends does not block the run.
+	stdinReader, stdinWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = stdinWriter.Close() }()
+	cmd := exec.Command("../blocc", "--history-file", historyFile, "--record", "true")
+	cmd.Stdin = stdinReader
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_ = stdinReader.Close()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected passing run to succeed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("Expected --record not to wait for stdin")
+	}
+	if _, err := run("", "true"); err != nil {
+		t.Fatalf("Expected unrecorded run to succeed: %v", err)
+	}
+
+	out, err := run("", "history")
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 {
+		t.Errorf("Expected header and 2 runs, got:\n%s", out)
+	}
+	if !strings.Contains(out, "blocked  session-1") || !strings.Contains(out, "1 passed, 1 failed in ") {
+		t.Errorf("Expected blocked run of session-1, got:\n%s", out)
+	}
+
+	out, err = run("", "history", "list", "--blocked")
+	if err != nil || strings.Count(out, "\n") != 2 {
+		t.Errorf("Expected only the blocked run, got %v:\n%s", err, out)
+	}
+
+	out, err = run("", "--format", "text", "history", "show", "last")
+	if err != nil || !strings.Contains(out, "--- PASS: true") || strings.Contains(out, "FAIL") {
+		t.Errorf("Expected last run to be the passing one, got %v:\n%s", err, out)
+	}
+
+	out, err = run("", "history", "stats")
+	if err != nil {
+		t.Fatalf("history stats failed: %v", err)
+	}
+	if !strings.Contains(out, "false  1     1         100%") || !strings.Contains(out, "true   2     0         0%") {
+		t.Errorf("Unexpected stats:\n%s", out)
+	}
+}
+
+func TestBlocc_SessionDiff(t *testing.T) {
+	stateDir := t.TempDir()
+	scriptPath := filepath.Join(t.TempDir(), "fail.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho \"$1 failed\" >&2\nexit 1\n"), 0700); err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	run := func(commands ...string) ErrorOutputWithDiff {
+		args := append([]string{"--diff"}, commands...)
+		cmd := exec.Command("../blocc", args...)
+		cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+stateDir)
+		cmd.Stdin = strings.NewReader(`{"session_id":"session-1","hook_event_name":"Stop"}`)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected exit code 2, got %v", err)
+		}
+
+		var output ErrorOutputWithDiff
+		if err := json.Unmarshal(stderr.Bytes(), &output); err != nil {
+			t.Fatalf("Failed to parse JSON output: %v\nOutput: %s", err, stderr.String())
+		}
+		return output
+	}
+
+	first := run(scriptPath+" lint", scriptPath+" test")
+	if first.Message != "2 command(s) failed (2 new, 0 remaining, 0 fixed)" {
+		t.Errorf("Unexpected first message %q", first.Message)
+	}
+
+	second := run(scriptPath+" test", scriptPath+" vet")
+	if second.Message != "2 command(s) failed (1 new, 1 remaining, 1 fixed)" {
+		t.Errorf("Unexpected second message %q", second.Message)
+	}
+
+	if len(second.Diff.Fixed) != 1 || second.Diff.Fixed[0] != scriptPath+" lint" {
+		t.Errorf("Expected lint to be fixed, got %+v", second.Diff)
+	}
+
+	for _, r := range second.Results {
+		unchanged := r.Command == scriptPath+" test"
+		if r.Unchanged != unchanged || (r.Stderr == "") != unchanged {
+			t.Errorf("Unexpected result %+v", r)
+		}
+	}
+}
+
+type ErrorOutputWithDiff struct {
+	Message string `json:"message"`
+	Results []struct {
+		Command   string `json:"command"`
+		Stderr    string `json:"stderr"`
+		Unchanged bool   `json:"unchanged"`
+	} `json:"results"`
+	Diff struct {
+		New       []string `json:"new"`
+		Remaining []string `json:"remaining"`
+		Fixed     []string `json:"fixed"`
+	} `json:"diff"`
+}
+
+func TestBlocc_Cache(t *testing.T) {
+	cacheDir := t.TempDir()
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "input.txt"), []byte("v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		args = append([]string{"--verbose", "--check", "name=check,cmd=true,input=*.txt,cwd=" + workDir}, args...)
+		cmd := exec.Command("../blocc", args...)
+		cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheDir)
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Expected exit code 0, got %v", err)
+		}
+		return stdout.String()
+	}
+
+	if out := run(); !strings.HasPrefix(out, "PASS    check") {
+		t.Errorf("Expected first run to execute, got %q", out)
+	}
+	if out := run(); out != "CACHED  check\n" {
+		t.Errorf("Expected second run to be cached, got %q", out)
+	}
+	if out := run("--no-cache"); !strings.HasPrefix(out, "PASS    check") {
+		t.Errorf("Expected --no-cache to execute, got %q", out)
+	}
+
+	cmd := exec.Command("../blocc", "cache", "prune", "--max-age", "0")
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheDir)
+	out, err := cmd.Output()
+	if err != nil || string(out) != "Removed 1 cached result(s)\n" {
+		t.Errorf("Unexpected cache prune output %q: %v", out, err)
+	}
+}
+
+func TestBlocc_CacheUnavailable(t *testing.T) {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "HOME=") && !strings.HasPrefix(kv, "XDG_CACHE_HOME=") {
+			env = append(env, kv)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantWarning bool
+	}{
+		{name: "no inputs", args: []string{"false"}},
+		{name: "inputs", args: []string{"--check", "name=check,cmd=false,input=*.txt"}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", tt.args...)
+			cmd.Env = env
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+			}
+			if got := strings.Contains(stderr.String(), "Warning: running without a cache"); got != tt.wantWarning {
+				t.Errorf("Expected cache warning %v, got stderr %q", tt.wantWarning, stderr.String())
+			}
+		})
+	}
+}
+
+func TestBlocc_Needs(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "built")
+
+	cmd := exec.Command("../blocc", "--parallel", "--verbose",
+		"--check", "name=test,cmd=test -f "+marker+",needs=build",
+		"--check", "name=build,cmd=touch "+marker,
+		"--check", "name=lint,cmd=false",
+		"--check", "name=fix,cmd=true,needs=lint",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+	}
+
+	wants := []string{"PASS    test (", "PASS    build (", "FAIL    lint (", `SKIP    fix (needs "lint", which failed)`}
+	for _, want := range wants {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected %q in status list, got:\n%s", want, stdout.String())
+		}
+	}
+
+	t.Run("cycle", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--check", "name=a,cmd=true,needs=b", "--check", "name=b,cmd=true,needs=a")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			t.Errorf("Expected exit code 1, got %v", err)
+		}
+
+		if !strings.Contains(stderr.String(), "dependency cycle: a -> b -> a") {
+			t.Errorf("Expected cycle error, got %q", stderr.String())
+		}
+	})
+}
+
+func TestBlocc_Retries(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "attempts")
+	script := filepath.Join(dir, "flaky.sh")
+	body := "#!/bin/sh\necho x >> " + counter + "\n[ $(wc -l < " + counter + ") -ge 2 ]\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", "--verbose", "--check", "name=e2e,cmd="+script+",retries=2,retry-delay=10ms")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Expected flaky check not to block, got %v", err)
+	}
+
+	if !strings.Contains(stdout.String(), "PASS    e2e (flaky, passed on attempt 2, ") {
+		t.Errorf("Expected flaky pass in status list, got %q", stdout.String())
+	}
+}
+
+func TestBlocc_Severity(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "spell.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho typo\nexit 1\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("warning does not block", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--check", "name=spell,cmd="+script+",stdout,severity=warn",
+			"--check", "name=todo,cmd=false,severity=ignore", "true")
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("Expected exit code 0, got %v", err)
+		}
+
+		var message struct {
+			SystemMessage string `json:"systemMessage"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &message); err != nil {
+			t.Fatalf("Expected systemMessage JSON on stdout, got %q: %v", stdout.String(), err)
+		}
+
+		for _, want := range []string{"1 warning(s), not blocking", "--- FAIL: spell", "typo", "1 warned"} {
+			if !strings.Contains(message.SystemMessage, want) {
+				t.Errorf("Expected systemMessage to contain %q, got %q", want, message.SystemMessage)
+			}
+		}
+		if strings.Contains(message.SystemMessage, "todo") {
+			t.Errorf("Expected ignored check to be left out, got %q", message.SystemMessage)
+		}
+	})
+
+	t.Run("warnings are reported with blocking failures", func(t *testing.T) {
+		cmd := exec.Command("../blocc", "--check", "name=spell,cmd=false,severity=warn", "--check", "name=test,cmd=false")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected exit code 2, got %v", err)
+		}
+
+		var errOut ErrorOutput
+		if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+			t.Fatalf("Failed to unmarshal stderr: %v", err)
+		}
+
+		if errOut.Message != "1 command(s) failed" || len(errOut.Results) != 2 || errOut.Results[1].Name != "spell" {
+			t.Errorf("Expected the blocking failure followed by the warning, got %+v", errOut)
+		}
+	})
+}
+
+func TestBlocc_StopPolicy(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "exit2.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 2\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdout []string
+	}{
+		{
+			name:       "default stops on exit code 2",
+			args:       []string{script, "false"},
+			wantStdout: []string{"FAIL    " + script, "SKIP    false (stopped after"},
+		},
+		{
+			name:       "continue always",
+			args:       []string{"--continue-always", script, "false"},
+			wantStdout: []string{"FAIL    " + script, "FAIL    false (exit code 1"},
+		},
+		{
+			name:       "fail fast",
+			args:       []string{"--fail-fast", "false", script},
+			wantStdout: []string{"FAIL    false", "SKIP    " + script + ` (stopped after "false" failed)`},
+		},
+		{
+			name: "stop on codes",
+			args: []string{"--stop-on-codes", "1,3", script, "false", "true"},
+			wantStdout: []string{
+				"FAIL    " + script, "FAIL    false", `SKIP    true (stopped after "false" exited with code 1)`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", append([]string{"--verbose"}, tt.args...)...)
+			var stdout bytes.Buffer
+			cmd.Stdout = &stdout
+
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Errorf("Expected exit code 2, got %v", err)
+			}
+
+			for _, want := range tt.wantStdout {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected stdout to contain %q, got %q", want, stdout.String())
+				}
+			}
+		})
+	}
+
+	t.Run("running checks finish", func(t *testing.T) {
+		slow := filepath.Join(t.TempDir(), "slow.sh")
+		if err := os.WriteFile(slow, []byte("#!/bin/sh\nsleep 1\necho slow failed >&2\nexit 1\n"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("../blocc", "--parallel", slow, script)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+			t.Fatalf("Expected exit code 2, got %v", err)
+		}
+		var errOut ErrorOutput
+		if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+			t.Fatalf("Failed to unmarshal stderr: %v", err)
+		}
+		if len(errOut.Results) != 2 || !strings.Contains(errOut.Results[0].Stderr, "slow failed") {
+			t.Errorf("Expected both failures to be reported, got %+v", errOut.Results)
+		}
+	})
+
+	cmd := exec.Command("../blocc", "--fail-fast", "--continue-always", "true")
+	if err := cmd.Run(); err == nil {
+		t.Error("Expected --fail-fast and --continue-always to be rejected together")
+	}
+}
+
+func TestBlocc_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "success code", args: []string{"--check", "name=grep,cmd=false,success-code=1"}, want: 0},
+		{name: "warning code", args: []string{"--check", "name=diff,cmd=false,warn-code=1"}, want: 0},
+		{name: "block exit code", args: []string{"--block-exit-code", "3", "false"}, want: 3},
+		{name: "error exit code", args: []string{"--error-exit-code", "4", "--check", "name=x"}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exec.Command("../blocc", tt.args...).Run()
+
+			got := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				got = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBlocc_EnvAndCwd(t *testing.T) {
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := t.TempDir()
+	api := filepath.Join(repo, "packages", "api")
+	for _, dir := range []string{filepath.Join(repo, ".git"), api} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(repo, "check.sh")
+	body := "#!/bin/sh\necho \"cwd=$(pwd) url=$API_URL token=$TOKEN\"\nexit 1\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".env"), []byte("HOST=localhost\nTOKEN=secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		wantCwd string
+	}{
+		{name: "repo root", wantCwd: api},
+		{
+			name:    "payload cwd",
+			args:    []string{"--cwd-base", "payload"},
+			stdin:   `{"session_id": "s1", "cwd": "` + filepath.Join(repo, "packages") + `"}`,
+			wantCwd: filepath.Join(repo, "packages", "packages", "api"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.stdin != "" {
+				if err := os.MkdirAll(tt.wantCwd, 0700); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := append([]string{"--env-file", "../.env",
+				"--check", "name=api,cmd=" + script + ",stdout,cwd=packages/api,env=API_URL=http://${HOST}/v1"}, tt.args...)
+			cmd := exec.Command(bloccPath, args...)
+			// Run from a subdirectory: relative cwd is resolved against the repo root.
+			cmd.Dir = filepath.Join(repo, "packages")
+			cmd.Stdin = strings.NewReader(tt.stdin)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+			}
+
+			var errOut struct {
+				Results []struct {
+					Stdout string   `json:"stdout"`
+					Cwd    string   `json:"cwd"`
+					Env    []string `json:"env"`
+				} `json:"results"`
+			}
+			if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+				t.Fatalf("Failed to unmarshal stderr: %v", err)
+			}
+
+			r := errOut.Results[0]
+			// Output normalization relativizes paths to the repo root.
+			rel, _ := filepath.Rel(repo, tt.wantCwd)
+			want := "cwd=" + rel + " url=http://localhost/v1 token=secret\n"
+			if r.Stdout != want || r.Cwd != tt.wantCwd {
+				t.Errorf("Expected stdout %q in %q, got %q in %q", want, tt.wantCwd, r.Stdout, r.Cwd)
+			}
+			if strings.Join(r.Env, ",") != "API_URL,HOST,TOKEN" {
+				t.Errorf("Expected env names recorded in the result, got %v", r.Env)
+			}
+		})
+	}
+}
+
+func TestBlocc_EnvFileSecretsNotReported(t *testing.T) {
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := t.TempDir()
+	state := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	const secret = "sk-secret-123"
+	if err := os.WriteFile(filepath.Join(repo, ".env"), []byte("API_TOKEN="+secret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "input.txt"), []byte("v1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"--check", "name=fail,cmd=false,env=AUTH=Bearer_${API_TOKEN}"},
+		{"--check", "name=cached,cmd=true,input=input.txt,env=AUTH=Bearer_${API_TOKEN}"},
+	} {
+		for _, report := range []string{"report.xml", "report.sarif", "report.json", "report.md"} {
+			args := append([]string{
+				"--env-file", ".env", "--record", "--diff", "--stdout",
+				"--history-file", filepath.Join(state, "history.jsonl"),
+				"--report-file", filepath.Join(state, report),
+			}, args...)
+			cmd := exec.Command(bloccPath, args...)
+			cmd.Dir = repo
+			cmd.Env = append(os.Environ(), "XDG_STATE_HOME="+state, "XDG_CACHE_HOME="+state)
+			cmd.Stdin = strings.NewReader(`{"session_id":"s1","hook_event_name":"Stop"}`)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			_ = cmd.Run()
+
+			if strings.Contains(stdout.String()+stderr.String(), secret) {
+				t.Errorf("Expected %v output without the env file value, got:\n%s%s", args, stdout.String(), stderr.String())
+			}
+		}
+	}
+
+	err = filepath.WalkDir(state, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %s without the env file value, got:\n%s", path, data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cached, _ := filepath.Glob(filepath.Join(state, "blocc", "*.json"))
+	sessions, _ := filepath.Glob(filepath.Join(state, "blocc", "sessions", "*.json"))
+	if len(cached) == 0 || len(sessions) == 0 {
+		t.Errorf("Expected cache and session files to be checked, got %v and %v", cached, sessions)
+	}
+}
+
+func TestBlocc_Workspaces(t *testing.T) {
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":         "",
+		"go.work":           "go 1.24\n\nuse (\n\t./api\n\t./web\n)\n",
+		"api/go.mod":        "module api\n",
+		"web/go.mod":        "module web\n",
+		"web/broken":        "",
+		"check.sh":          "#!/bin/sh\necho \"checked $(basename $(pwd))\"\n[ ! -f broken ]\n",
+		"unrelated/file.md": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(bloccPath, "--format", "text", "--verbose",
+		"--check", "name=check,cmd="+filepath.Join(repo, "check.sh")+",stdout,foreach=workspace:go", "true")
+	cmd.Dir = repo
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+	}
+
+	for _, want := range []string{"PASS    true (", "PASS    check (api) (", "FAIL    check (web) (exit code 1"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected status list to contain %q, got %q", want, stdout.String())
+		}
+	}
+
+	want := "--- FAIL: check (web) (exit code 1)\npackage: web\n"
+	if !strings.Contains(stderr.String(), want) || !strings.Contains(stderr.String(), "checked web") {
+		t.Errorf("Expected failure of the web package, got %q", stderr.String())
+	}
+}
+
+func TestBlocc_Watch(t *testing.T) {
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bloccPath, "watch", "--interval", "20ms", "--debounce", "50ms", "test -f ready")
+	cmd.Dir = repo
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cmd.Process.Kill() }()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("blocc watch exited before printing %q", want)
+				}
+				if strings.Contains(line, want) {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Timed out waiting for %q", want)
+			}
+		}
+	}
+
+	waitFor("exit code 2, Claude is blocked")
+	if err := os.WriteFile(filepath.Join(repo, "ready"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("changed: ready")
+	waitFor("exit code 0, Claude continues")
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for range lines {
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected blocc watch to exit cleanly on interrupt, got %v", err)
+	}
+}
+
+func TestBlocc_Version(t *testing.T) {
+	cmd := exec.Command("../blocc", "--version")
+	output, err := cmd.Output()
+
+	if err != nil {
+		t.Fatalf("Version command failed: %v", err)
+	}
+
+	if !strings.Contains(string(output), "blocc version") {
+		t.Errorf("Expected version output to contain 'blocc version', got %q", string(output))
+	}
+}
+
+// validateInitResult validates the generated settings file structure
+func validateInitResult(t *testing.T, tmpDir, expectedCommand string) {
+	settingsPath := filepath.Join(tmpDir, ".claude", "settings.local.json")
+	if _, statErr := os.Stat(settingsPath); os.IsNotExist(statErr) {
+		t.Errorf("settings.local.json was not created at %s", settingsPath)
+		return
+	}
+
+	content, err := os.ReadFile(settingsPath)
+	if err != nil {
+		t.Fatalf("Failed to read settings file: %v", err)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		t.Fatalf("Generated file is not valid JSON: %v", err)
+	}
+
+	hooks, ok := result["hooks"].(map[string]interface{})
+	if !ok {
+		t.Error("Missing 'hooks' field")
+		return
+	}
+
+	stopHooks, ok := hooks["Stop"].([]interface{})
+	if !ok {
+		t.Error("Missing 'Stop' field")
+		return
+	}
+
+	if len(stopHooks) != 1 {
+		t.Errorf("Expected 1 Stop entry, got %d", len(stopHooks))
+		return
+	}
+
+	entry := stopHooks[0].(map[string]interface{})
+	matcher, ok := entry["matcher"].(string)
+	if !ok || matcher != "" {
+		t.Errorf("Expected empty matcher, got %q", matcher)
+	}
+
+	entryHooks, ok := entry["hooks"].([]interface{})
+	if !ok || len(entryHooks) != 1 {
+		t.Errorf("Expected 1 hook entry, got %d", len(entryHooks))
+		return
+	}
+
+	hook := entryHooks[0].(map[string]interface{})
+	hookType, ok := hook["type"].(string)
+	if !ok || hookType != "command" {
+		t.Errorf("Expected hook type 'command', got %q", hookType)
+	}
+
+	command, ok := hook["command"].(string)
+	if !ok || command != expectedCommand {
+		t.Errorf("Expected command %q, got %q", expectedCommand, command)
+	}
+}
+
+func TestBlocc_Init(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedCommand string
+	}{
+		{
+			name:            "init with custom command",
+			args:            []string{"--init", "npm run lint"},
+			expectedCommand: `blocc 'npm run lint'`,
+		},
+		{
+			name:            "init with custom message and commands",
+			args:            []string{"--init", "--message", "Custom error", "npm run lint", "npm run test"},
+			expectedCommand: `blocc --message "Custom error" 'npm run lint' 'npm run test'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+
+			bloccPath, err := filepath.Abs("../blocc")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cmd := exec.Command(bloccPath, tt.args...)
+			cmd.Dir = tmpDir
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			err = cmd.Run()
+			if err != nil {
+				t.Fatalf("Init command failed: %v, stderr: %s", err, stderr.String())
+			}
+
+			outputStr := stdout.String()
+			if !strings.Contains(outputStr, "Successfully created settings.local.json") {
+				t.Errorf("Expected success message, got %q", outputStr)
+			}
+
+			validateInitResult(t, tmpDir, tt.expectedCommand)
+		})
+	}
+}
+
+func TestBlocc_InitFileAlreadyExists(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Create the .claude directory and settings file first
+	claudeDir := filepath.Join(tmpDir, ".claude")
+	if err := os.MkdirAll(claudeDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	settingsPath := filepath.Join(claudeDir, "settings.local.json")
+	if err := os.WriteFile(settingsPath, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Try to init again - should fail
+	cmd := exec.Command(bloccPath, "--init", "echo test")
+	cmd.Dir = tmpDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err == nil {
+		t.Error("Expected command to fail when file already exists")
+		return
+	}
+
+	stderrStr := stderr.String()
+	if !strings.Contains(stderrStr, "already exists") {
+		t.Errorf("Expected error message to contain 'already exists', got %q", stderrStr)
+	}
+}
+
+func TestBlocc_InitPathDisplay(t *testing.T) {
+	// Test that path display shows ~ when possible
+	tmpDir := t.TempDir()
+
+	bloccPath, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(bloccPath, "--init", "echo test")
+	cmd.Dir = tmpDir
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("Init command failed: %v", err)
+	}
 
 	outputStr := stdout.String()
 	if !strings.Contains(outputStr, "Successfully created settings.local.json") {
@@ -397,3 +1547,343 @@ func TestBlocc_InitPathDisplay(t *testing.T) {
 		t.Errorf("Expected path to contain '.claude/settings.local.json', got %q", outputStr)
 	}
 }
+
+func TestBlocc_Stream(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "build.sh")
+	body := "#!/bin/sh\necho compiling\necho 'main.go:3: undefined: x' >&2\nexit 1\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", "--stream", "--check", "name=build,cmd="+script, "--check", "name=lint,cmd=echo clean")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit code 2, got %v", err)
+	}
+
+	for _, want := range []string{"build | compiling\n", "build | main.go:3: undefined: x\n", "lint  | clean\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("Expected streamed stdout to contain %q, got %q", want, stdout.String())
+		}
+	}
+
+	var errOut ErrorOutput
+	if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+		t.Fatalf("Failed to unmarshal stderr: %v", err)
+	}
+	if len(errOut.Results) != 1 || !strings.Contains(errOut.Results[0].Stderr, "undefined: x") {
+		t.Errorf("Expected the captured failure in the report, got %+v", errOut)
+	}
+}
+
+func TestBlocc_LargeOutput(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "flood.sh")
+	// About 23MB of output, far over the 4mb default capture limit.
+	body := "#!/bin/sh\nseq 1 3000000\necho 'FAIL: TestFlood'\nexit 1\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	const outputBytes = 22888896 + len("FAIL: TestFlood\n")
+
+	tests := []struct {
+		name string
+		args []string
+		want func(stdout string) bool
+	}{
+		{
+			name: "head and tail",
+			args: []string{"--stdout"},
+			want: func(stdout string) bool {
+				return len(stdout) < 4<<20+100 && strings.HasPrefix(stdout, "1\n2\n") &&
+					strings.Contains(stdout, "... [truncated ") && strings.HasSuffix(stdout, "3000000\nFAIL: TestFlood\n")
+			},
+		},
+		{
+			name: "filter reads the whole output",
+			args: []string{"--stdout", "--stdout-pipe", "grep:^(1500000|FAIL)"},
+			want: func(stdout string) bool { return stdout == "1500000\nFAIL: TestFlood\n" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", append(tt.args, script)...)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("Expected exit code 2, got %v", err)
+			}
+
+			var errOut ErrorOutput
+			if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+				t.Fatalf("Failed to unmarshal stderr: %v", err)
+			}
+			result := errOut.Results[0]
+			if result.StdoutBytes != outputBytes {
+				t.Errorf("Expected stdoutBytes %d, got %d", outputBytes, result.StdoutBytes)
+			}
+			if !tt.want(result.Stdout) {
+				t.Errorf("Unexpected stdout of %d bytes: %.100q", len(result.Stdout), result.Stdout)
+			}
+		})
+	}
+}
+
+func TestBlocc_StdinAndPayloadEnv(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "lint.sh")
+	body := "#!/bin/sh\necho \"event=$BLOCC_EVENT file=$BLOCC_FILE_PATH session=$BLOCC_SESSION_ID\"\ncat\nexit 1\n"
+	if err := os.WriteFile(script, []byte(body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "files.txt")
+	if err := os.WriteFile(input, []byte("a.go\nb.go\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	payload := `{"session_id":"s1","hook_event_name":"PostToolUse","tool_name":"Write",` +
+		`"tool_input":{"file_path":"/tmp/app/main.go","content":"package main"}}`
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "payload",
+			args: []string{"--stdin", "payload", script},
+			want: "event=PostToolUse file=/tmp/app/main.go session=s1\n" + payload,
+		},
+		{
+			name: "file per check",
+			args: []string{"--payload", "--check", "name=lint,cmd=" + script + ",stdin=" + input},
+			want: "event=PostToolUse file=/tmp/app/main.go session=s1\na.go\nb.go\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", append([]string{"--stdout"}, tt.args...)...)
+			cmd.Stdin = strings.NewReader(payload)
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+
+			err := cmd.Run()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+				t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+			}
+
+			var errOut ErrorOutput
+			if err := json.Unmarshal(stderr.Bytes(), &errOut); err != nil {
+				t.Fatalf("Failed to unmarshal stderr: %v", err)
+			}
+			if got := errOut.Results[0].Stdout; got != tt.want {
+				t.Errorf("Expected stdout %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBlocc_Simulate(t *testing.T) {
+	blocc, err := filepath.Abs("../blocc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".git"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, ".claude"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	scripts := map[string]string{
+		"lint.sh":  "#!/bin/sh\necho lint failed >&2\nexit 1\n",
+		"check.sh": "#!/bin/sh\ncase \"$BLOCC_FILE_PATH\" in *bad.go) echo bad file >&2; exit 1;; esac\n",
+	}
+	for name, body := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	settings := map[string]any{
+		"hooks": map[string]any{
+			"Stop": []any{map[string]any{
+				"matcher": "",
+				"hooks":   []any{map[string]any{"type": "command", "command": blocc + " ./lint.sh"}},
+			}},
+			"PostToolUse": []any{map[string]any{
+				"matcher": "Edit|Write",
+				"hooks":   []any{map[string]any{"type": "command", "command": blocc + " --payload ./check.sh"}},
+			}},
+		},
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".claude", "settings.json"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		want         []string
+	}{
+		{
+			name: "stop blocks",
+			args: []string{"simulate", "--stop-hook-active"},
+			want: []string{
+				`"stop_hook_active": true`, "Exit code: 2", "lint failed", "blocks stopping", "stop_hook_active was true",
+			},
+		},
+		{
+			name: "edit of a good file",
+			args: []string{"simulate", "PostToolUse", "--file", "ok.go"},
+			want: []string{`"tool_name": "Edit"`, filepath.Join(dir, "ok.go"), "Exit code: 0", "Claude carries on"},
+		},
+		{
+			name: "edit of a bad file",
+			args: []string{"simulate", "PostToolUse", "--file", "bad.go"},
+			want: []string{"Exit code: 2", "bad file", "stderr is fed to Claude"},
+		},
+		{
+			name:         "no matching hook",
+			args:         []string{"simulate", "PostToolUse", "--tool", "Bash"},
+			wantExitCode: 1,
+		},
+		{
+			name: "command override",
+			args: []string{"simulate", "UserPromptSubmit", "--prompt", "hello", "--command", "exit 3"},
+			want: []string{`"prompt": "hello"`, "Exit code: 3", "non-blocking error"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(blocc, tt.args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "HOME="+dir)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+
+			err := cmd.Run()
+			exitCode := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				exitCode = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if exitCode != tt.wantExitCode {
+				t.Fatalf("Expected exit code %d, got %d: %s", tt.wantExitCode, exitCode, stderr.String())
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(stdout.String(), want) {
+					t.Errorf("Expected stdout to contain %q, got:\n%s", want, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestBlocc_TimeoutWithBackgroundChildren(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 10 &\nsleep 10\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", "--check", "name=slow,cmd="+script+",timeout=1s")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Expected the timeout to stop the check within 4s, took %s", elapsed)
+	}
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		t.Fatalf("Expected exit code 2, got %v: %s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "timed out after 1s") {
+		t.Errorf("Expected a timeout in stderr, got %s", stderr.String())
+	}
+}
+
+func TestBlocc_ExitedWithBackgroundChildren(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "serve.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nsleep 5 &\nexit 0\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("../blocc", script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Expected the check to pass, got %v: %s", err, stderr.String())
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Expected blocc not to wait for the background sleep, took %s", elapsed)
+	}
+}
+
+func TestBlocc_StdinReadOnlyWhenPayloadIsUsed(t *testing.T) {
+	// A pipe whose writer never closes, like a CI runner's stdin.
+	stdin, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	defer w.Close()
+
+	cmd := exec.Command("../blocc", "true")
+	cmd.Stdin = stdin
+	done := make(chan error, 1)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected success, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("Expected blocc not to wait for stdin when nothing uses the payload")
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "payload flag", args: []string{"--payload", "true"}},
+		{name: "payload variable", args: []string{"--check", "name=echo,cmd=echo $BLOCC_EVENT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("../blocc", tt.args...)
+			cmd.Stdin = strings.NewReader("not json")
+			var stderr bytes.Buffer
+			cmd.Stderr = &stderr
+			if err := cmd.Run(); err != nil {
+				t.Fatalf("Expected success, got %v: %s", err, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), "Warning: invalid hook payload") {
+				t.Errorf("Expected a warning about the invalid payload, got %q", stderr.String())
+			}
+		})
+	}
+}
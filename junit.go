@@ -0,0 +1,121 @@
+package blocc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr,omitempty"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr,omitempty"`
+	Time      string          `xml:"time,attr,omitempty"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// JUnitReporter writes a JUnit XML report with one test case per command.
+type JUnitReporter struct{}
+
+func (JUnitReporter) Report(w io.Writer, output ErrorOutput) error {
+	suite := junitTestSuite{Name: "blocc"}
+	var total time.Duration
+
+	for _, r := range output.Results {
+		tc := junitTestCase{
+			Name:      resultTitle(r),
+			ClassName: "blocc",
+			Time:      junitTime(time.Duration(r.Duration)),
+			SystemOut: r.Stdout,
+			SystemErr: r.Stderr,
+		}
+		total += time.Duration(r.Duration)
+		if suite.Timestamp == "" && !r.StartedAt.IsZero() {
+			suite.Timestamp = r.StartedAt.UTC().Format("2006-01-02T15:04:05")
+		}
+
+		switch {
+		case r.Status == StatusSkipped || r.Status == StatusCancelled:
+			tc.Skipped = &junitSkipped{Message: string(r.Status)}
+			suite.Skipped++
+		case resultFailed(r) && (r.Severity == "" || r.Severity == SeverityBlock):
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s exited with code %d", r.Command, r.ExitCode),
+				Type:    "exitCode",
+				Content: strings.TrimSpace(strings.Join([]string{r.Stderr, r.Stdout}, "\n")),
+			}
+			suite.Failures++
+		case resultFailed(r):
+			// Warnings and ignored failures pass, as they do not fail blocc either.
+			tc.SystemErr = strings.Join(resultNotes(r), "\n") + "\n" + tc.SystemErr
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Time = junitTime(total)
+
+	report := junitTestSuites{
+		Name:     output.Message,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	if output.Summary != nil {
+		report.Time = junitTime(time.Duration(output.Summary.Duration))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats d in seconds as JUnit expects, or returns "" for unknown durations.
+func junitTime(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,77 @@
+package blocc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJUnitReporter(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{Name: "lint", Command: "make lint", ExitCode: 1, Stderr: "lint <failed> & stopped\n"},
+		{Command: "make test", ExitCode: 0, Stdout: "ok\n"},
+	})
+
+	var buf bytes.Buffer
+	if err := (JUnitReporter{}).Report(&buf, output); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("Report() output missing XML header: %q", buf.String())
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Report() produced invalid XML: %v", err)
+	}
+
+	if report.Tests != 2 || report.Failures != 1 || len(report.Suites) != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+
+	cases := report.Suites[0].TestCases
+	if cases[0].Name != "lint" || cases[0].Failure == nil {
+		t.Errorf("expected failing lint test case, got %+v", cases[0])
+	}
+
+	if cases[0].Failure.Content != "lint <failed> & stopped" {
+		t.Errorf("failure content = %q", cases[0].Failure.Content)
+	}
+
+	if cases[1].Name != "make test" || cases[1].Failure != nil || cases[1].SystemOut != "ok\n" {
+		t.Errorf("expected passing make test case, got %+v", cases[1])
+	}
+}
+
+func TestJUnitReporterStatus(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{Name: "grep", Command: "grep -r TODO .", ExitCode: 1, Status: StatusPassed},
+		{Name: "audit", Command: "npm audit", ExitCode: 3, Status: StatusFailed, Severity: SeverityWarn, Stderr: "1 vuln\n"},
+		{Name: "test", Command: "go test ./...", ExitCode: 1, Status: StatusFailed},
+	})
+
+	var buf bytes.Buffer
+	if err := (JUnitReporter{}).Report(&buf, output); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Report() produced invalid XML: %v", err)
+	}
+
+	if report.Failures != 1 {
+		t.Errorf("Failures = %d, want only the blocking failure", report.Failures)
+	}
+	cases := report.Suites[0].TestCases
+	if cases[0].Failure != nil {
+		t.Errorf("success code test case = %+v, want passing", cases[0])
+	}
+	if cases[1].Failure != nil || cases[1].SystemErr != "warning, not blocking\n1 vuln\n" {
+		t.Errorf("warning test case = %+v, want passing with a note", cases[1])
+	}
+	if cases[2].Failure == nil {
+		t.Errorf("failed test case = %+v, want a failure", cases[2])
+	}
+}
@@ -0,0 +1,100 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NormalizeEnv asks child processes for plain, uncolored output.
+// Check environment variables take precedence over these.
+var NormalizeEnv = map[string]string{
+	"NO_COLOR":         "1",
+	"CLICOLOR":         "0",
+	"FORCE_COLOR":      "0",
+	"CARGO_TERM_COLOR": "never",
+	"TERM":             "dumb",
+}
+
+// Normalizer cleans command output for LLM consumption: it strips ANSI escape
+// sequences, collapses carriage-return progress lines and rewrites absolute
+// paths under Root as repo-relative paths.
+type Normalizer struct {
+	Root string
+}
+
+func NewNormalizer(root string) Normalizer {
+	return Normalizer{Root: root}
+}
+
+func (n Normalizer) Normalize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	s = StripANSI(s)
+	s = CollapseCarriageReturns(s)
+	return n.relativizePaths(s)
+}
+
+// CollapseCarriageReturns keeps only the text after the last carriage return
+// of each line, which is what a terminal would finally display.
+func CollapseCarriageReturns(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndexByte(line, '\r'); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (n Normalizer) relativizePaths(s string) string {
+	for _, root := range n.roots() {
+		s = strings.ReplaceAll(s, root+string(filepath.Separator), "")
+	}
+	return s
+}
+
+// roots returns Root and its symlink-resolved form, longest first.
+func (n Normalizer) roots() []string {
+	if n.Root == "" || n.Root == string(filepath.Separator) {
+		return nil
+	}
+
+	roots := []string{filepath.Clean(n.Root)}
+	if resolved, err := filepath.EvalSymlinks(n.Root); err == nil && resolved != roots[0] {
+		roots = append(roots, resolved)
+	}
+	if len(roots) == 2 && len(roots[1]) > len(roots[0]) {
+		roots[0], roots[1] = roots[1], roots[0]
+	}
+	return roots
+}
+
+// FindRepoRoot returns the nearest ancestor of dir containing a .git entry,
+// or dir itself when there is none.
+func FindRepoRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+
+	for current := abs; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,84 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "home", "user", "repo")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "strip ANSI colors",
+			input: "\x1b[1m\x1b[31merror\x1b[0m: mismatched types\n",
+			want:  "error: mismatched types\n",
+		},
+		{
+			name:  "strip OSC hyperlinks",
+			input: "see \x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\\n",
+			want:  "see docs\n",
+		},
+		{
+			name:  "collapse progress lines",
+			input: "Compiling 1/3\rCompiling 2/3\rCompiling 3/3\nFinished\n",
+			want:  "Compiling 3/3\nFinished\n",
+		},
+		{
+			name:  "windows line endings",
+			input: "line one\r\nline two\r\n",
+			want:  "line one\nline two\n",
+		},
+		{
+			name:  "relativize absolute paths",
+			input: root + "/src/main.rs:10:5: error\n  --> " + root + "/src/lib.rs:1:1\n",
+			want:  "src/main.rs:10:5: error\n  --> src/lib.rs:1:1\n",
+		},
+		{
+			name:  "leave paths outside the root",
+			input: "/usr/lib/go/src/fmt/print.go:1\n",
+			want:  "/usr/lib/go/src/fmt/print.go:1\n",
+		},
+	}
+
+	normalizer := NewNormalizer(root)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizer.Normalize(tt.input); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilesystemRoot(t *testing.T) {
+	input := "/usr/bin/env: not found\n"
+	if got := NewNormalizer("/").Normalize(input); got != input {
+		t.Errorf("Normalize() = %q, want %q", got, input)
+	}
+}
+
+func TestFindRepoRoot(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "packages", "api")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindRepoRoot(nested); got != nested {
+		t.Errorf("FindRepoRoot() without .git = %q, want %q", got, nested)
+	}
+
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindRepoRoot(nested); got != root {
+		t.Errorf("FindRepoRoot() = %q, want %q", got, root)
+	}
+}
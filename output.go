@@ -1,32 +1,356 @@
 package blocc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 )
 
 type ErrorOutput struct {
-	Message string   `json:"message"`
-	Results []Result `json:"results"`
+	Message string       `json:"message"`
+	Results []Result     `json:"results"`
+	Summary *Summary     `json:"summary,omitempty"`
+	Diff    *SessionDiff `json:"diff,omitempty"`
 }
 
-func OutputError(message string, results []Result) error {
+// Reporter renders the error output for Claude or a human reader.
+type Reporter interface {
+	Report(w io.Writer, output ErrorOutput) error
+}
+
+func NewReporter(format string) (Reporter, error) {
+	switch format {
+	case "", "json":
+		return JSONReporter{}, nil
+	case "compact":
+		return JSONReporter{Compact: true}, nil
+	case "text":
+		return TextReporter{}, nil
+	case "markdown":
+		return MarkdownReporter{}, nil
+	case "junit":
+		return JUnitReporter{}, nil
+	case "sarif":
+		return SARIFReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// ReportFormatForPath infers a report format from the file extension of path.
+func ReportFormatForPath(path string) (string, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".xml"):
+		return "junit", nil
+	case strings.HasSuffix(lower, ".sarif"), strings.HasSuffix(lower, ".sarif.json"):
+		return "sarif", nil
+	case strings.HasSuffix(lower, ".json"):
+		return "json", nil
+	case strings.HasSuffix(lower, ".md"):
+		return "markdown", nil
+	case strings.HasSuffix(lower, ".txt"):
+		return "text", nil
+	default:
+		return "", fmt.Errorf("cannot infer report format from %q, use --report-format", path)
+	}
+}
+
+// WriteReportFile writes output to path using reporter, replacing any existing file.
+func WriteReportFile(path string, reporter Reporter, output ErrorOutput) error {
+	var buf bytes.Buffer
+	if err := reporter.Report(&buf, output); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+
+	return nil
+}
+
+func NewErrorOutput(message string, results []Result) ErrorOutput {
 	if message == "" {
-		message = fmt.Sprintf("%d command(s) failed", len(results))
+		message = fmt.Sprintf("%d command(s) failed", countBlocking(results))
 	}
 
-	output := ErrorOutput{
+	return ErrorOutput{
 		Message: message,
 		Results: results,
 	}
+}
 
-	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+// OutputError writes results as JSON to stderr.
+//
+// Deprecated: Use WriteError, or Run with WithReport, to choose the writer.
+func OutputError(message string, results []Result) error {
+	return WriteError(os.Stderr, JSONReporter{}, NewErrorOutput(message, results))
+}
+
+// WriteError writes output to w using reporter.
+func WriteError(w io.Writer, reporter Reporter, output ErrorOutput) error {
+	if err := reporter.Report(w, output); err != nil {
+		return fmt.Errorf("failed to write error output: %w", err)
+	}
+	return nil
+}
+
+// countBlocking counts the results that block. Results without a status are
+// counted too, as they predate result statuses and were only kept on failure.
+func countBlocking(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Blocking() || (r.Status == "" && r.Severity == "") {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteStatusList writes one line per result with its status and duration.
+func WriteStatusList(w io.Writer, results []Result) error {
+	var b strings.Builder
+	for _, r := range results {
+		b.WriteString(StatusLine(r) + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// StatusLine describes a result in one line, e.g. "FAIL    test (exit code 1, 1.2s)".
+func StatusLine(r Result) string {
+	var details []string
+	if r.Failed() || r.Status == StatusCancelled {
+		details = append(details, fmt.Sprintf("exit code %d", r.ExitCode))
+	}
+	if r.Failed() && r.Severity == SeverityIgnore {
+		details = append(details, "ignored")
+	}
+	if r.Flaky {
+		details = append(details, fmt.Sprintf("flaky, passed on attempt %d", r.Attempts))
+	} else if r.Attempts > 1 {
+		details = append(details, fmt.Sprintf("%d attempts", r.Attempts))
+	}
+	if r.SkipReason != "" {
+		details = append(details, r.SkipReason)
+	}
+	if r.Status != StatusSkipped && r.Status != StatusCached {
+		details = append(details, r.Duration.String())
+	}
+
+	label := statusLabel(r.Status)
+	if r.Failed() && r.Severity == SeverityWarn {
+		label = "WARN"
+	}
+	line := fmt.Sprintf("%-7s %s", label, resultTitle(r))
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+	return line
+}
+
+func statusLabel(status Status) string {
+	switch status {
+	case StatusPassed:
+		return "PASS"
+	case StatusTimedOut:
+		return "TIMEOUT"
+	case StatusCancelled:
+		return "CANCEL"
+	case StatusSkipped:
+		return "SKIP"
+	case StatusCached:
+		return "CACHED"
+	default:
+		return "FAIL"
+	}
+}
+
+// JSONReporter writes indented JSON, or single-line JSON when Compact is set.
+type JSONReporter struct {
+	Compact bool
+}
+
+func (r JSONReporter) Report(w io.Writer, output ErrorOutput) error {
+	var jsonBytes []byte
+	var err error
+	if r.Compact {
+		jsonBytes, err = json.Marshal(output)
+	} else {
+		jsonBytes, err = json.MarshalIndent(output, "", "  ")
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to marshal error output: %v\n", err)
-		return err
+		return fmt.Errorf("failed to marshal error output: %w", err)
 	}
 
-	fmt.Fprintln(os.Stderr, string(jsonBytes))
-	return nil
+	_, err = fmt.Fprintln(w, string(jsonBytes))
+	return err
+}
+
+// TextReporter writes plain text meant for people running blocc in a terminal.
+type TextReporter struct{}
+
+func (TextReporter) Report(w io.Writer, output ErrorOutput) error {
+	var b strings.Builder
+	b.WriteString(output.Message + "\n")
+	if output.Diff != nil && len(output.Diff.Fixed) > 0 {
+		fmt.Fprintf(&b, "fixed: %s\n", strings.Join(output.Diff.Fixed, ", "))
+	}
+
+	for _, r := range output.Results {
+		if r.Status == "" || r.Failed() {
+			fmt.Fprintf(&b, "\n--- FAIL: %s (exit code %d)\n", resultTitle(r), r.ExitCode)
+		} else {
+			fmt.Fprintf(&b, "\n--- %s: %s\n", statusLabel(r.Status), resultTitle(r))
+		}
+		if r.Package != "" {
+			fmt.Fprintf(&b, "package: %s\n", r.Package)
+		}
+		if r.Name != "" {
+			fmt.Fprintf(&b, "command: %s\n", r.Command)
+		}
+		for _, note := range resultNotes(r) {
+			fmt.Fprintf(&b, "note: %s\n", note)
+		}
+		writeTextStream(&b, "stdout", r.Stdout)
+		writeTextStream(&b, "stderr", r.Stderr)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeTextStream(b *strings.Builder, name, content string) {
+	if content == "" {
+		return
+	}
+	fmt.Fprintf(b, "[%s]\n%s", name, content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// MarkdownReporter renders each result as a heading followed by fenced output blocks.
+type MarkdownReporter struct{}
+
+func (MarkdownReporter) Report(w io.Writer, output ErrorOutput) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", output.Message)
+	if output.Diff != nil && len(output.Diff.Fixed) > 0 {
+		fixed := make([]string, len(output.Diff.Fixed))
+		for i, title := range output.Diff.Fixed {
+			fixed[i] = markdownCode(title)
+		}
+		fmt.Fprintf(&b, "\nFixed: %s\n", strings.Join(fixed, ", "))
+	}
+
+	for _, r := range output.Results {
+		if r.Status == "" || r.Failed() {
+			fmt.Fprintf(&b, "\n## %s (exit code %d)\n", markdownCode(resultTitle(r)), r.ExitCode)
+		} else {
+			fmt.Fprintf(&b, "\n## %s (%s)\n", markdownCode(resultTitle(r)), r.Status)
+		}
+		if r.Package != "" {
+			fmt.Fprintf(&b, "\nPackage: %s\n", markdownCode(r.Package))
+		}
+		if r.Name != "" {
+			fmt.Fprintf(&b, "\nCommand: %s\n", markdownCode(r.Command))
+		}
+		for _, note := range resultNotes(r) {
+			fmt.Fprintf(&b, "\n> %s\n", note)
+		}
+		writeMarkdownStream(&b, "stdout", r.Stdout)
+		writeMarkdownStream(&b, "stderr", r.Stderr)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownStream(b *strings.Builder, name, content string) {
+	if content == "" {
+		return
+	}
+	fence := markdownFence(content)
+	fmt.Fprintf(b, "\n**%s**\n\n%stext\n%s", name, fence, content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(fence + "\n")
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in content.
+func markdownFence(content string) string {
+	longest, current := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func markdownCode(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// resultFailed reports whether r failed. Results without a status, such as
+// ones built by hand, failed when they exited with a non-zero code.
+func resultFailed(r Result) bool {
+	if r.Status == "" {
+		return r.ExitCode != 0
+	}
+	return r.Failed()
+}
+
+func resultTitle(r Result) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Command
+}
+
+// resultNotes describes result metadata that text formats show next to the output.
+func resultNotes(r Result) []string {
+	var notes []string
+	if r.Failed() && r.Severity == SeverityIgnore {
+		notes = append(notes, "failure ignored, not blocking")
+	}
+	if r.Failed() && r.Severity == SeverityWarn {
+		notes = append(notes, "warning, not blocking")
+	}
+	if r.SkipReason != "" {
+		notes = append(notes, "skipped: "+r.SkipReason)
+	}
+	if r.Flaky {
+		notes = append(notes, fmt.Sprintf("flaky: passed on attempt %d", r.Attempts))
+	} else if r.Attempts > 1 {
+		notes = append(notes, fmt.Sprintf("failed %d attempts", r.Attempts))
+	}
+	if r.Unchanged {
+		notes = append(notes, "output unchanged since the previous run, omitted")
+	}
+	if r.Signal != "" {
+		notes = append(notes, "killed by signal: "+r.Signal)
+	}
+	for _, fe := range r.FilterErrors {
+		notes = append(notes, "filter error: "+fe.Error())
+	}
+	if r.OriginalStdoutBytes > 0 {
+		notes = append(notes, fmt.Sprintf("stdout truncated from %d bytes", r.OriginalStdoutBytes))
+	}
+	if r.OriginalStderrBytes > 0 {
+		notes = append(notes, fmt.Sprintf("stderr truncated from %d bytes", r.OriginalStderrBytes))
+	}
+	return notes
 }
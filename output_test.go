@@ -1,8 +1,11 @@
 package blocc
 
 import (
+	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestOutputError(t *testing.T) {
@@ -105,3 +108,152 @@ func validateErrorOutput(t *testing.T, _ string, results []Result, wantMessage s
 		t.Errorf("Results count mismatch: got %v, want %v", len(unmarshaled.Results), len(expectedOutput.Results))
 	}
 }
+
+func TestReporters(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{
+			Name:     "lint",
+			Command:  "golangci-lint run",
+			ExitCode: 1,
+			Stdout:   "main.go:1:1: use ``` carefully",
+			Stderr:   "lint failed\n",
+		},
+		{
+			Command:             "go test ./...",
+			ExitCode:            2,
+			Stderr:              "--- FAIL: TestX\n",
+			OriginalStderrBytes: 4096,
+			FilterErrors: []FilterError{
+				{Stream: "stderr", Filter: "sh:broken", ExitCode: 127, Message: "exit status 127"},
+			},
+		},
+	})
+
+	tests := []struct {
+		format       string
+		wantContains []string
+	}{
+		{
+			format: "json",
+			wantContains: []string{
+				"{\n  \"message\": \"2 command(s) failed\",",
+				`"name": "lint"`,
+			},
+		},
+		{
+			format: "compact",
+			wantContains: []string{
+				`{"message":"2 command(s) failed","results":[{"name":"lint","command":"golangci-lint run"`,
+			},
+		},
+		{
+			format: "text",
+			wantContains: []string{
+				"2 command(s) failed\n",
+				"--- FAIL: lint (exit code 1)\ncommand: golangci-lint run\n",
+				"[stdout]\nmain.go:1:1: use ``` carefully\n[stderr]\nlint failed\n",
+				"--- FAIL: go test ./... (exit code 2)\n",
+				"note: filter error: stderr filter \"sh:broken\": exit status 127\n",
+				"note: stderr truncated from 4096 bytes\n",
+			},
+		},
+		{
+			format: "markdown",
+			wantContains: []string{
+				"# 2 command(s) failed\n",
+				"## `lint` (exit code 1)\n\nCommand: `golangci-lint run`\n",
+				"**stdout**\n\n````text\nmain.go:1:1: use ``` carefully\n````\n",
+				"**stderr**\n\n```text\nlint failed\n```\n",
+				"## `go test ./...` (exit code 2)\n",
+				"> stderr truncated from 4096 bytes\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			reporter, err := NewReporter(tt.format)
+			if err != nil {
+				t.Fatalf("NewReporter() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := reporter.Report(&buf, output); err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Report() output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+
+	if _, err := NewReporter("yaml"); err == nil {
+		t.Error("NewReporter(yaml) expected error")
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   string
+	}{
+		{result: Result{Command: "true", Status: StatusPassed, Duration: Duration(time.Second)}, want: "PASS    true (1s)"},
+		{
+			result: Result{Name: "test", Command: "go test", Status: StatusFailed, ExitCode: 1, Duration: Duration(time.Second)},
+			want:   "FAIL    test (exit code 1, 1s)",
+		},
+		{
+			result: Result{Command: "false", Status: StatusFailed, ExitCode: 1, Severity: SeverityIgnore},
+			want:   "FAIL    false (exit code 1, ignored, 0s)",
+		},
+		{
+			result: Result{Command: "lint", Status: StatusFailed, ExitCode: 1, Severity: SeverityWarn},
+			want:   "WARN    lint (exit code 1, 0s)",
+		},
+		{
+			result: Result{Command: "sleep 9", Status: StatusTimedOut, ExitCode: 124},
+			want:   "TIMEOUT sleep 9 (exit code 124, 0s)",
+		},
+		{result: Result{Command: "make", Status: StatusSkipped}, want: "SKIP    make"},
+		{
+			result: Result{Command: "e2e", Status: StatusPassed, Attempts: 2, Flaky: true, Duration: Duration(time.Second)},
+			want:   "PASS    e2e (flaky, passed on attempt 2, 1s)",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := StatusLine(tt.result); got != tt.want {
+			t.Errorf("StatusLine() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewErrorOutputCountsBlockingResults(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{Command: "true", Status: StatusPassed},
+		{Command: "false", Status: StatusFailed, ExitCode: 1},
+		{Command: "optional", Status: StatusFailed, ExitCode: 1, Severity: SeverityIgnore},
+		{Command: "lint", Status: StatusFailed, ExitCode: 1, Severity: SeverityWarn},
+		{Command: "make", Status: StatusSkipped},
+	})
+
+	if output.Message != "1 command(s) failed" {
+		t.Errorf("NewErrorOutput() message = %q", output.Message)
+	}
+
+	var buf bytes.Buffer
+	if err := (TextReporter{}).Report(&buf, output); err != nil {
+		t.Fatal(err)
+	}
+
+	wants := []string{"--- PASS: true\n", "--- FAIL: false (exit code 1)\n", "note: failure ignored",
+		"note: warning, not blocking", "--- SKIP: make\n"}
+	for _, want := range wants {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("TextReporter output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package blocc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// HookPayload is the JSON object Claude Code writes to a hook's stdin.
+type HookPayload struct {
+	SessionID      string          `json:"session_id,omitempty"`
+	TranscriptPath string          `json:"transcript_path,omitempty"`
+	Cwd            string          `json:"cwd,omitempty"`
+	HookEventName  string          `json:"hook_event_name,omitempty"`
+	ToolName       string          `json:"tool_name,omitempty"`
+	ToolInput      json.RawMessage `json:"tool_input,omitempty"`
+
+	// Raw is the payload exactly as read.
+	Raw []byte `json:"-"`
+}
+
+// ReadHookPayload parses a hook payload from r. It returns nil without error
+// when r is empty.
+func ReadHookPayload(r io.Reader) (*HookPayload, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook payload: %w", err)
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		return nil, nil
+	}
+
+	var payload HookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("invalid hook payload: %w", err)
+	}
+
+	payload.Raw = data
+	return &payload, nil
+}
+
+// ReadStdinHookPayload reads the hook payload from stdin unless stdin is a
+// terminal, so that blocc run by hand does not wait for input.
+func ReadStdinHookPayload() (*HookPayload, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return nil, nil
+	}
+	return ReadHookPayload(os.Stdin)
+}
+
+// FilePath returns the file a tool such as Edit or Write operated on, if any.
+func (p *HookPayload) FilePath() string {
+	var input struct {
+		FilePath     string `json:"file_path"`
+		NotebookPath string `json:"notebook_path"`
+	}
+	if len(p.ToolInput) == 0 || json.Unmarshal(p.ToolInput, &input) != nil {
+		return ""
+	}
+	if input.FilePath != "" {
+		return input.FilePath
+	}
+	return input.NotebookPath
+}
+
+// Env returns the payload as the BLOCC_SESSION_ID, BLOCC_EVENT, BLOCC_CWD and
+// BLOCC_FILE_PATH environment variables, leaving out empty values.
+func (p *HookPayload) Env() map[string]string {
+	env := make(map[string]string)
+	for name, value := range map[string]string{
+		"BLOCC_SESSION_ID": p.SessionID,
+		"BLOCC_EVENT":      p.HookEventName,
+		"BLOCC_CWD":        p.Cwd,
+		"BLOCC_FILE_PATH":  p.FilePath(),
+	} {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	return env
+}
@@ -0,0 +1,72 @@
+package blocc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadHookPayload(t *testing.T) {
+	payload, err := ReadHookPayload(strings.NewReader(`{"session_id":"abc123","transcript_path":"/tmp/t.jsonl",` +
+		`"cwd":"/repo","hook_event_name":"Stop","stop_hook_active":false}`))
+	if err != nil {
+		t.Fatalf("ReadHookPayload() error = %v", err)
+	}
+
+	if payload.SessionID != "abc123" || payload.HookEventName != "Stop" || payload.Cwd != "/repo" {
+		t.Errorf("ReadHookPayload() = %+v", payload)
+	}
+	if !strings.HasSuffix(string(payload.Raw), `"stop_hook_active":false}`) {
+		t.Errorf("ReadHookPayload() Raw = %q, want the payload as read", payload.Raw)
+	}
+
+	if payload, err := ReadHookPayload(strings.NewReader(" \n")); payload != nil || err != nil {
+		t.Errorf("ReadHookPayload(empty) = %+v, %v, want nil, nil", payload, err)
+	}
+
+	if _, err := ReadHookPayload(strings.NewReader("not json")); err == nil {
+		t.Error("ReadHookPayload() expected error for invalid JSON")
+	}
+}
+
+func TestHookPayloadEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    map[string]string
+	}{
+		{
+			name:    "stop",
+			payload: `{"session_id":"s1","cwd":"/repo","hook_event_name":"Stop"}`,
+			want:    map[string]string{"BLOCC_SESSION_ID": "s1", "BLOCC_CWD": "/repo", "BLOCC_EVENT": "Stop"},
+		},
+		{
+			name: "edit",
+			payload: `{"session_id":"s1","hook_event_name":"PostToolUse","tool_name":"Edit",` +
+				`"tool_input":{"file_path":"/repo/main.go","old_string":"a","new_string":"b"}}`,
+			want: map[string]string{"BLOCC_SESSION_ID": "s1", "BLOCC_EVENT": "PostToolUse", "BLOCC_FILE_PATH": "/repo/main.go"},
+		},
+		{
+			name:    "notebook",
+			payload: `{"hook_event_name":"PostToolUse","tool_input":{"notebook_path":"/repo/a.ipynb"}}`,
+			want:    map[string]string{"BLOCC_EVENT": "PostToolUse", "BLOCC_FILE_PATH": "/repo/a.ipynb"},
+		},
+		{
+			name:    "bash",
+			payload: `{"hook_event_name":"PreToolUse","tool_input":{"command":"ls"}}`,
+			want:    map[string]string{"BLOCC_EVENT": "PreToolUse"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := ReadHookPayload(strings.NewReader(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := payload.Env(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Env() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
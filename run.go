@@ -0,0 +1,176 @@
+package blocc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Report is the outcome of Run.
+type Report struct {
+	// Results holds a result for every check, grouped by package.
+	Results []Result
+	Summary Summary
+}
+
+// Blocking returns the failed results that block.
+func (r Report) Blocking() []Result {
+	return FailedResults(r.Results)
+}
+
+// Warnings returns the failed results reported without blocking.
+func (r Report) Warnings() []Result {
+	return WarningResults(r.Results)
+}
+
+// ExitCode returns the exit code of a hook running the checks: 2 when a result
+// blocks, so that Claude Code feeds the report back to Claude, and 0 otherwise.
+func (r Report) ExitCode() int {
+	if len(r.Blocking()) > 0 {
+		return 2
+	}
+	return 0
+}
+
+// ErrorOutput returns what a hook reports for the run, truncated to opts: the
+// blocking failures followed by the warnings, or the warnings alone. It
+// returns false when there is nothing to report.
+func (r Report) ErrorOutput(opts TruncateOptions) (ErrorOutput, bool) {
+	blocking, warnings := r.Blocking(), r.Warnings()
+	if len(blocking) == 0 && len(warnings) == 0 {
+		return ErrorOutput{}, false
+	}
+
+	output := NewErrorOutput("", TruncateResults(append(blocking, warnings...), opts))
+	if len(blocking) == 0 {
+		output.Message = fmt.Sprintf("%d warning(s), not blocking", len(warnings))
+	}
+	summary := r.Summary
+	output.Summary = &summary
+	return output, true
+}
+
+// Option configures Run.
+type Option func(*runOptions)
+
+type runOptions struct {
+	executor    *Executor
+	concurrency int
+	w           io.Writer
+	reporter    Reporter
+	truncate    TruncateOptions
+}
+
+// WithRunner runs the processes of checks with runner instead of os/exec.
+func WithRunner(runner Runner) Option {
+	return func(o *runOptions) { o.executor.WithRunner(runner) }
+}
+
+// WithConcurrency runs at most n checks at once. By default, and when n is 0,
+// all ready checks run at once.
+func WithConcurrency(n int) Option {
+	return func(o *runOptions) { o.concurrency = n }
+}
+
+// WithStopPolicy sets when the run stops early. It defaults to DefaultStopPolicy.
+func WithStopPolicy(policy StopPolicy) Option {
+	return func(o *runOptions) { o.executor.WithStopPolicy(policy) }
+}
+
+// WithFilterErrorPolicy sets how failing output filters are handled. By default
+// the unfiltered output is reported along with the failure, see FilterErrorAnnotate.
+func WithFilterErrorPolicy(policy FilterErrorPolicy) Option {
+	return func(o *runOptions) { o.executor.WithFilterErrorPolicy(policy) }
+}
+
+// WithCache reuses results of checks with inputs from cache.
+func WithCache(cache *Cache) Option {
+	return func(o *runOptions) { o.executor.WithCache(cache) }
+}
+
+// WithBaseDir resolves relative check directories against dir instead of the
+// repository root of the current directory. Checks running for each package
+// expand to the packages of the repository at dir, and paths in their output
+// are made relative to its root.
+func WithBaseDir(dir string) Option {
+	return func(o *runOptions) { o.executor.WithBaseDir(dir) }
+}
+
+// WithHookPayload passes a hook payload to the checks, see Executor.WithHookPayload.
+func WithHookPayload(payload *HookPayload) Option {
+	return func(o *runOptions) { o.executor.WithHookPayload(payload) }
+}
+
+// WithReport writes the report of failures and warnings to w with reporter,
+// or as JSON when reporter is nil, once the checks finish. Nothing is written
+// when every check passes.
+func WithReport(w io.Writer, reporter Reporter) Option {
+	return func(o *runOptions) {
+		o.w, o.reporter = w, reporter
+		if reporter == nil {
+			o.reporter = JSONReporter{}
+		}
+	}
+}
+
+// WithTruncate shrinks the output in the report written by WithReport to opts.
+func WithTruncate(opts TruncateOptions) Option {
+	return func(o *runOptions) { o.truncate = opts }
+}
+
+// OnStart calls fn when a check starts.
+func OnStart(fn func(Check)) Option {
+	return func(o *runOptions) { o.executor.OnStart(fn) }
+}
+
+// OnResult calls fn with the result of every check as soon as it is known.
+func OnResult(fn func(Result)) Option {
+	return func(o *runOptions) { o.executor.OnResult(fn) }
+}
+
+// Run runs checks as the blocc command does and returns their results. Checks
+// are used as given, so unset fields such as IncludeStdout keep their zero
+// value, except that checks with Foreach are expanded for each package, see
+// ExpandWorkspaces. Callbacks are called one at a time.
+//
+// Run returns an error when the checks are invalid or cannot be expanded,
+// when a filter fails with FilterErrorFail, when writing the report fails, or
+// with ctx.Err() when ctx is done before the checks finish. The report holds
+// the results in all but the first case.
+func Run(ctx context.Context, checks []Check, opts ...Option) (Report, error) {
+	o := runOptions{executor: NewExecutorWithPipelines(false, nil, nil, false)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	checks, err := ExpandWorkspaces(checks, FindRepoRoot(o.executor.BaseDir()))
+	if err != nil {
+		return Report{}, err
+	}
+
+	start := time.Now()
+	results, err := o.executor.ExecuteChecksContext(ctx, checks, o.concurrency)
+	if results == nil && err != nil {
+		return Report{}, err
+	}
+
+	results = GroupByPackage(results)
+	report := Report{Results: results, Summary: NewSummary(results, time.Since(start))}
+	if err != nil {
+		return report, err
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	if o.w != nil {
+		if output, ok := report.ErrorOutput(o.truncate); ok {
+			if err := WriteError(o.w, o.reporter, output); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,283 @@
+package blocc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/blocctest"
+)
+
+func TestRun(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("make lint", blocctest.Response{Stdout: "ok\n"}).
+		On("make test", blocctest.Response{Stderr: "FAIL: TestAdd\n", ExitCode: 1}).
+		On("make spell", blocctest.Response{Stderr: "typo\n", ExitCode: 1})
+	lint := blocc.Check{Name: "lint", Command: "make lint"}
+	test := blocc.Check{Name: "test", Command: "make test"}
+	spell := blocc.Check{Name: "spell", Command: "make spell", Severity: blocc.SeverityWarn}
+
+	tests := []struct {
+		name         string
+		checks       []blocc.Check
+		wantExitCode int
+		wantReport   string
+	}{
+		{name: "passed", checks: []blocc.Check{lint}},
+		{
+			name:         "blocking failure and warning",
+			checks:       []blocc.Check{lint, test, spell},
+			wantExitCode: 2,
+			wantReport:   `"message": "1 command(s) failed"`,
+		},
+		{name: "warning", checks: []blocc.Check{lint, spell}, wantReport: `"message": "1 warning(s), not blocking"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			var started, finished []string
+			report, err := blocc.Run(context.Background(), tt.checks,
+				blocc.WithRunner(runner),
+				blocc.WithStopPolicy(blocc.StopPolicy{}),
+				blocc.WithReport(&out, nil),
+				blocc.OnStart(func(c blocc.Check) { started = append(started, c.Name) }),
+				blocc.OnResult(func(r blocc.Result) { finished = append(finished, r.Name) }),
+			)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if report.ExitCode() != tt.wantExitCode || report.Summary.Total != len(tt.checks) {
+				t.Errorf("Run() exit code %d, summary %+v, want %d", report.ExitCode(), report.Summary, tt.wantExitCode)
+			}
+			if tt.wantReport == "" && out.Len() > 0 || !strings.Contains(out.String(), tt.wantReport) {
+				t.Errorf("report = %q, want containing %q", out.String(), tt.wantReport)
+			}
+
+			var names []string
+			for _, c := range tt.checks {
+				names = append(names, c.Name)
+			}
+			sort.Strings(started)
+			sort.Strings(finished)
+			sort.Strings(names)
+			if !reflect.DeepEqual(started, names) || !reflect.DeepEqual(finished, names) {
+				t.Errorf("OnStart got %v, OnResult got %v, want %v", started, finished, names)
+			}
+		})
+	}
+}
+
+func TestRunReportOutput(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("go vet ./...", blocctest.Response{Stderr: "main.go:3: unreachable code\n", ExitCode: 1})
+
+	var out bytes.Buffer
+	report, err := blocc.Run(context.Background(), []blocc.Check{{Command: "go vet ./..."}},
+		blocc.WithRunner(runner), blocc.WithReport(&out, blocc.TextReporter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := report.Blocking(); len(got) != 1 || got[0].Stderr != "main.go:3: unreachable code\n" {
+		t.Errorf("Blocking() = %+v", got)
+	}
+	if !strings.Contains(out.String(), "--- FAIL: go vet ./... (exit code 1)") {
+		t.Errorf("text report = %q", out.String())
+	}
+}
+
+func TestRunScheduling(t *testing.T) {
+	delay := blocctest.Response{Delay: 20 * time.Millisecond}
+	runner := blocctest.NewRunner().On("gen", delay).On("a", delay).On("b", delay).On("c", delay).On("report")
+
+	checks := []blocc.Check{
+		{Name: "gen", Command: "gen"},
+		{Name: "a", Command: "a", Needs: []string{"gen"}},
+		{Name: "b", Command: "b", Needs: []string{"gen"}},
+		{Name: "c", Command: "c", Needs: []string{"gen"}},
+		{Name: "report", Command: "report", Needs: []string{"a", "b", "c"}},
+	}
+	if _, err := blocc.Run(context.Background(), checks, blocc.WithRunner(runner), blocc.WithConcurrency(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := runner.Commands()
+	if commands[0] != "gen" || commands[4] != "report" {
+		t.Errorf("commands ran in order %v, want gen first and report last", commands)
+	}
+	if got := runner.MaxConcurrent(); got != 2 {
+		t.Errorf("MaxConcurrent() = %d, want 2", got)
+	}
+}
+
+func TestRunStopPolicyLetsRunningChecksFinish(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("e2e", blocctest.Response{Stderr: "e2e failed\n", ExitCode: 1, Delay: 50 * time.Millisecond}).
+		On("lint", blocctest.Response{Stderr: "bad\n", ExitCode: 2, Delay: 10 * time.Millisecond}).
+		On("docs")
+
+	checks := []blocc.Check{
+		{Name: "e2e", Command: "e2e"},
+		{Name: "lint", Command: "lint"},
+		{Name: "docs", Command: "docs"},
+	}
+	report, err := blocc.Run(context.Background(), checks, blocc.WithRunner(runner), blocc.WithConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []blocc.Status
+	for _, r := range report.Results {
+		statuses = append(statuses, r.Status)
+	}
+	want := []blocc.Status{blocc.StatusFailed, blocc.StatusFailed, blocc.StatusSkipped}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if len(report.Blocking()) != 2 {
+		t.Errorf("Blocking() = %+v, want e2e and lint", report.Blocking())
+	}
+	calls := runner.Calls()
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Command < calls[j].Command })
+	if len(calls) != 2 || calls[0].Command != "e2e" || calls[0].Killed {
+		t.Errorf("calls = %+v, want e2e to finish and docs never run", calls)
+	}
+}
+
+func TestRunTimeoutAndRetries(t *testing.T) {
+	runner := blocctest.NewRunner().
+		On("hang", blocctest.Response{Block: true}).
+		On("flaky", blocctest.Response{ExitCode: 1}, blocctest.Response{ExitCode: 1}, blocctest.Response{})
+
+	checks := []blocc.Check{
+		{Name: "hang", Command: "hang", Timeout: 10 * time.Millisecond},
+		{Name: "flaky", Command: "flaky", Retries: 3},
+	}
+	report, err := blocc.Run(context.Background(), checks,
+		blocc.WithRunner(runner), blocc.WithStopPolicy(blocc.StopPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hang, flaky := report.Results[0], report.Results[1]
+	if hang.Status != blocc.StatusTimedOut || hang.ExitCode != 124 {
+		t.Errorf("hang = %s with exit code %d, want timed out with 124", hang.Status, hang.ExitCode)
+	}
+	if flaky.Status != blocc.StatusPassed || !flaky.Flaky || flaky.Attempts != 3 {
+		t.Errorf("flaky = %+v, want passed after 3 attempts", flaky)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	runner := blocctest.NewRunner().On("sleep", blocctest.Response{Block: true}).On("true")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checks := []blocc.Check{{Name: "slow", Command: "sleep"}, {Name: "next", Command: "true", Needs: []string{"slow"}}}
+	report, err := blocc.Run(ctx, checks, blocc.WithRunner(runner), blocc.OnStart(func(blocc.Check) { cancel() }))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+
+	statuses := []blocc.Status{report.Results[0].Status, report.Results[1].Status}
+	if want := []blocc.Status{blocc.StatusCancelled, blocc.StatusSkipped}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}
+
+type failingFilter struct{}
+
+func (failingFilter) Apply(string) (string, error) { return "", errors.New("filter failed") }
+
+func TestRunFilterErrorPolicy(t *testing.T) {
+	runner := blocctest.NewRunner().On("lint", blocctest.Response{Stderr: "bad\n", ExitCode: 1})
+	checks := []blocc.Check{{Name: "lint", Command: "lint", StderrPipeline: blocc.Pipeline{failingFilter{}}}}
+
+	tests := []struct {
+		policy     blocc.FilterErrorPolicy
+		wantErr    bool
+		wantErrors int
+	}{
+		{policy: blocc.FilterErrorFallback},
+		{policy: blocc.FilterErrorAnnotate, wantErrors: 1},
+		{policy: blocc.FilterErrorFail, wantErr: true, wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			report, err := blocc.Run(context.Background(), checks,
+				blocc.WithRunner(runner), blocc.WithFilterErrorPolicy(tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			lint := report.Results[0]
+			if lint.Stderr != "bad\n" || len(lint.FilterErrors) != tt.wantErrors {
+				t.Errorf("lint = stderr %q with filter errors %v, want the unfiltered output with %d error(s)",
+					lint.Stderr, lint.FilterErrors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestRunInvalidChecks(t *testing.T) {
+	checks := []blocc.Check{{Name: "a", Command: "true", Needs: []string{"missing"}}}
+	report, err := blocc.Run(context.Background(), checks, blocc.WithRunner(blocctest.NewRunner()))
+	if err == nil || report.Results != nil {
+		t.Errorf("Run() = %+v, %v, want an error without results", report, err)
+	}
+}
+
+func TestRunForeach(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{".git", "api", "web"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"go.work":    "use (\n\t./api\n\t./web\n)\n",
+		"api/go.mod": "module api\n",
+		"web/go.mod": "module web\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runner := blocctest.NewRunner().
+		On("go vet ./...", blocctest.Response{Stderr: root + "/api/main.go:3: unreachable code\n", ExitCode: 1})
+	checks := []blocc.Check{{Name: "vet", Command: "go vet ./...", Foreach: true, WorkspaceKind: blocc.WorkspaceGo}}
+	report, err := blocc.Run(context.Background(), checks, blocc.WithRunner(runner), blocc.WithBaseDir(root))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var names, dirs []string
+	for _, r := range report.Results {
+		names = append(names, r.Name)
+	}
+	for _, call := range runner.Calls() {
+		dirs = append(dirs, call.Dir)
+	}
+	sort.Strings(dirs)
+	if want := []string{"vet (api)", "vet (web)"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Run() results = %q, want %q", names, want)
+	}
+	if want := []string{filepath.Join(root, "api"), filepath.Join(root, "web")}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("Run() ran in %q, want %q", dirs, want)
+	}
+	if stderr := report.Results[0].Stderr; stderr != "api/main.go:3: unreachable code\n" {
+		t.Errorf("Run() stderr = %q, want paths relative to the repository root", stderr)
+	}
+}
@@ -0,0 +1,102 @@
+package blocc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// processWaitDelay is how long a Runner waits for the output of a process to
+// close once the process exited or its context is done. Background children,
+// such as a server started by a test script, may keep the output open.
+const processWaitDelay = time.Second
+
+// Process is a command for a Runner to run.
+type Process struct {
+	// Args holds the command name followed by its arguments.
+	Args []string
+	Dir  string
+	// Env is added to the inherited environment.
+	Env    []string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ExitStatus describes how a process exited.
+type ExitStatus struct {
+	Code int
+	// Signal names the signal that killed the process, if any.
+	Signal string
+	// Executable is the resolved path of the command, if known.
+	Executable string
+}
+
+// Runner runs the processes of checks. Run returns once the process exited,
+// killing it when ctx is done. A non-zero exit code is not an error: Run
+// returns an error only when the process could not run at all.
+type Runner interface {
+	Run(ctx context.Context, p Process) (ExitStatus, error)
+}
+
+// ExecRunner runs processes with os/exec. It is the default Runner.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, p Process) (ExitStatus, error) {
+	// #nosec G204 - This is a CLI tool designed to execute user-provided commands
+	cmd := exec.CommandContext(ctx, p.Args[0], p.Args[1:]...)
+	cmd.Dir = p.Dir
+	if len(p.Env) > 0 {
+		cmd.Env = append(os.Environ(), p.Env...)
+	}
+	cmd.Stdin = p.Stdin
+	cmd.Stdout = p.Stdout
+	cmd.Stderr = p.Stderr
+	cmd.WaitDelay = processWaitDelay
+
+	var status ExitStatus
+	if cmd.Err == nil {
+		status.Executable = executablePath(cmd.Path, p.Dir)
+	}
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		status.Code = exitErr.ExitCode()
+		status.Signal = exitSignal(exitErr)
+		return status, nil
+	case errors.Is(err, exec.ErrWaitDelay):
+		// The process exited successfully but left children holding its
+		// output, which was cut off. The exit status is still its own.
+		status.Code = cmd.ProcessState.ExitCode()
+		return status, nil
+	}
+	return status, err
+}
+
+// exitSignal returns the name of the signal that killed the process, if any.
+func exitSignal(exitError *exec.ExitError) string {
+	status, ok := exitError.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+	return status.Signal().String()
+}
+
+// executablePath returns the absolute path of the command at path, which is
+// relative to dir when it is a relative path such as ./script.sh.
+func executablePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(filepath.Join(dir, path)); err == nil {
+		return abs
+	}
+	return path
+}
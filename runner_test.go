@@ -0,0 +1,109 @@
+package blocc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecRunner(t *testing.T) {
+	tests := []struct {
+		name       string
+		process    Process
+		wantCode   int
+		wantSignal string
+		wantStdout string
+		wantErr    error
+	}{
+		{name: "success", process: Process{Args: []string{"echo", "hi"}}, wantStdout: "hi\n"},
+		{name: "exit code", process: Process{Args: []string{"sh", "-c", "exit 3"}}, wantCode: 3},
+		{
+			name:       "signal",
+			process:    Process{Args: []string{"sh", "-c", "kill -TERM $$"}},
+			wantCode:   -1,
+			wantSignal: "terminated",
+		},
+		{
+			name:       "env, dir and stdin",
+			process:    Process{Args: []string{"sh", "-c", "echo $MODE; pwd; cat"}, Env: []string{"MODE=test"}, Dir: "/"},
+			wantStdout: "test\n/\ninput",
+		},
+		{name: "not found", process: Process{Args: []string{"blocc-no-such-command"}}, wantErr: exec.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			tt.process.Stdout = &stdout
+			tt.process.Stdin = strings.NewReader("input")
+
+			status, err := ExecRunner{}.Run(context.Background(), tt.process)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", err, tt.wantErr)
+			}
+			if status.Code != tt.wantCode || status.Signal != tt.wantSignal || stdout.String() != tt.wantStdout {
+				t.Errorf("Run() = %+v with stdout %q, want code %d, signal %q, stdout %q",
+					status, stdout.String(), tt.wantCode, tt.wantSignal, tt.wantStdout)
+			}
+			if err == nil && !strings.HasSuffix(status.Executable, "/"+tt.process.Args[0]) {
+				t.Errorf("Run() Executable = %q, want the path of %s", status.Executable, tt.process.Args[0])
+			}
+		})
+	}
+}
+
+func TestExecRunnerRelativeExecutable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "check.sh"), []byte("#!/bin/sh\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := ExecRunner{}.Run(context.Background(), Process{Args: []string{"./check.sh"}, Dir: dir})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := filepath.Join(dir, "check.sh"); status.Executable != want {
+		t.Errorf("Run() Executable = %q, want %q", status.Executable, want)
+	}
+}
+
+func TestExecRunnerBackgroundChildren(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		timeout  time.Duration
+		wantCode int
+		killed   bool
+	}{
+		{name: "timeout", command: "sleep 10 & sleep 10", timeout: 100 * time.Millisecond, wantCode: -1, killed: true},
+		{name: "exited", command: "echo started; sleep 10 &"},
+		{name: "exited with code", command: "sleep 10 & exit 3", wantCode: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			var stdout bytes.Buffer
+			start := time.Now()
+			status, err := ExecRunner{}.Run(ctx, Process{Args: []string{"sh", "-c", tt.command}, Stdout: &stdout})
+			if elapsed := time.Since(start); elapsed > tt.timeout+3*processWaitDelay {
+				t.Errorf("Run() took %s, want it not to wait for the background sleep", elapsed)
+			}
+			if err != nil || status.Code != tt.wantCode || (status.Signal != "") != tt.killed {
+				t.Errorf("Run() = %+v, %v, want code %d, killed %v", status, err, tt.wantCode, tt.killed)
+			}
+		})
+	}
+}
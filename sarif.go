@@ -0,0 +1,184 @@
+package blocc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations,omitempty"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifInvocation struct {
+	CommandLine         string                 `json:"commandLine"`
+	ExecutionSuccessful bool                   `json:"executionSuccessful"`
+	ExitCode            int                    `json:"exitCode"`
+	ExitSignalName      string                 `json:"exitSignalName,omitempty"`
+	StartTimeUTC        string                 `json:"startTimeUtc,omitempty"`
+	EndTimeUTC          string                 `json:"endTimeUtc,omitempty"`
+	WorkingDirectory    *sarifArtifactLocation `json:"workingDirectory,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// SARIFReporter writes a SARIF 2.1.0 log with one run per command. Results come
+// from findings parsed from the command output; a failed command without any
+// finding is reported as a single result without location.
+type SARIFReporter struct{}
+
+func (SARIFReporter) Report(w io.Writer, output ErrorOutput) error {
+	report := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    make([]sarifRun, 0, len(output.Results)),
+	}
+
+	for _, r := range output.Results {
+		report.Runs = append(report.Runs, sarifRunFor(r))
+	}
+
+	jsonBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SARIF report: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(jsonBytes))
+	return err
+}
+
+func sarifRunFor(r Result) sarifRun {
+	name := resultTitle(r)
+	run := sarifRun{
+		Tool:        sarifTool{Driver: sarifDriver{Name: name}},
+		Invocations: []sarifInvocation{sarifInvocationFor(r)},
+		Results:     []sarifResult{},
+	}
+
+	// Output of a check that did not fail, such as a passing linter's notices,
+	// is not reported as findings.
+	var findings []Finding
+	if resultFailed(r) {
+		findings = append(ParseFindings(r.Stdout), ParseFindings(r.Stderr)...)
+	}
+	maxLevel := sarifLevel(r.Severity)
+	for _, f := range findings {
+		result := sarifResult{
+			RuleID:  name,
+			Level:   capLevel(f.Level, maxLevel),
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.File},
+					Region:           &sarifRegion{StartLine: f.Line, StartColumn: f.Column},
+				},
+			}},
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	if len(findings) == 0 && resultFailed(r) {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  name,
+			Level:   sarifLevel(r.Severity),
+			Message: sarifMessage{Text: fmt.Sprintf("%s exited with code %d", r.Command, r.ExitCode)},
+		})
+	}
+
+	return run
+}
+
+// sarifLevel returns the level of the result reporting a failed check.
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityWarn:
+		return "warning"
+	case SeverityIgnore:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+var sarifLevelRank = map[string]int{"none": 0, "note": 1, "warning": 2, "error": 3}
+
+// capLevel lowers level to maxLevel, so that findings of a warning check are no errors.
+func capLevel(level, maxLevel string) string {
+	if sarifLevelRank[level] > sarifLevelRank[maxLevel] {
+		return maxLevel
+	}
+	return level
+}
+
+func sarifInvocationFor(r Result) sarifInvocation {
+	invocation := sarifInvocation{
+		CommandLine:         r.Command,
+		ExecutionSuccessful: !resultFailed(r),
+		ExitCode:            r.ExitCode,
+		ExitSignalName:      r.Signal,
+	}
+
+	if !r.StartedAt.IsZero() {
+		invocation.StartTimeUTC = r.StartedAt.UTC().Format(time.RFC3339Nano)
+		invocation.EndTimeUTC = r.StartedAt.Add(time.Duration(r.Duration)).UTC().Format(time.RFC3339Nano)
+	}
+
+	if r.Dir != "" {
+		dir := (&url.URL{Scheme: "file", Path: filepath.ToSlash(r.Dir) + "/"}).String()
+		invocation.WorkingDirectory = &sarifArtifactLocation{URI: dir}
+	}
+
+	return invocation
+}
@@ -0,0 +1,119 @@
+package blocc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSARIFReporter(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{Name: "vet", Command: "go vet ./...", ExitCode: 1, Stderr: "./main.go:10:5: undefined: foo\n"},
+		{Command: "make build", ExitCode: 2, Stderr: "build failed\n"},
+	})
+
+	var buf bytes.Buffer
+	if err := (SARIFReporter{}).Report(&buf, output); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	var report sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Report() produced invalid JSON: %v", err)
+	}
+
+	if report.Version != "2.1.0" || len(report.Runs) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	vet := report.Runs[0]
+	if vet.Tool.Driver.Name != "vet" || len(vet.Results) != 1 {
+		t.Fatalf("unexpected vet run: %+v", vet)
+	}
+
+	location := vet.Results[0].Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "./main.go" ||
+		location.Region.StartLine != 10 || location.Region.StartColumn != 5 {
+		t.Errorf("unexpected vet location: %+v", location)
+	}
+
+	build := report.Runs[1]
+	if len(build.Results) != 1 || len(build.Results[0].Locations) != 0 || build.Results[0].Level != "error" {
+		t.Errorf("expected one unlocated error for build, got %+v", build.Results)
+	}
+}
+
+func TestReportFormatForPath(t *testing.T) {
+	tests := map[string]string{
+		"report.xml":         "junit",
+		"results.sarif":      "sarif",
+		"results.sarif.json": "sarif",
+		"out.json":           "json",
+		"REPORT.MD":          "markdown",
+		"out.txt":            "text",
+	}
+
+	for path, want := range tests {
+		got, err := ReportFormatForPath(path)
+		if err != nil || got != want {
+			t.Errorf("ReportFormatForPath(%q) = %q, %v, want %q", path, got, err, want)
+		}
+	}
+
+	if _, err := ReportFormatForPath("report.html"); err == nil {
+		t.Error("ReportFormatForPath(report.html) expected error")
+	}
+}
+
+func TestSARIFReporterStatus(t *testing.T) {
+	output := NewErrorOutput("", []Result{
+		{Name: "grep", Command: "grep -r TODO .", ExitCode: 1, Status: StatusPassed},
+		{Name: "audit", Command: "npm audit", ExitCode: 3, Status: StatusFailed, Severity: SeverityWarn},
+		{Name: "test", Command: "go test ./...", ExitCode: 1, Status: StatusFailed},
+		{
+			Name: "lint", Command: "golangci-lint run", ExitCode: 1, Status: StatusPassed,
+			Stdout: "main.go:3:1: unused variable\n",
+		},
+		{
+			Name: "vet", Command: "go vet ./...", ExitCode: 1, Status: StatusFailed, Severity: SeverityWarn,
+			Stderr: "main.go:3:1: undefined: foo\nmain.go:4:1: note: declared here\n",
+		},
+		{
+			Name: "spell", Command: "cspell .", ExitCode: 1, Status: StatusFailed, Severity: SeverityIgnore,
+			Stdout: "README.md:1:1: unknown word\n",
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := (SARIFReporter{}).Report(&buf, output); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	var report sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Report() produced invalid JSON: %v", err)
+	}
+
+	tests := []struct {
+		successful bool
+		levels     []string
+	}{
+		{successful: true},
+		{levels: []string{"warning"}},
+		{levels: []string{"error"}},
+		{successful: true},
+		{levels: []string{"warning", "note"}},
+		{levels: []string{"note"}},
+	}
+	for i, tt := range tests {
+		run := report.Runs[i]
+		var levels []string
+		for _, r := range run.Results {
+			levels = append(levels, r.Level)
+		}
+		if run.Invocations[0].ExecutionSuccessful != tt.successful || !reflect.DeepEqual(levels, tt.levels) {
+			t.Errorf("run %s = successful %v with levels %v, want %v with %v", run.Tool.Driver.Name,
+				run.Invocations[0].ExecutionSuccessful, levels, tt.successful, tt.levels)
+		}
+	}
+}
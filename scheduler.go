@@ -0,0 +1,263 @@
+package blocc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ValidateNeeds checks that every need names another check and that the needs
+// contain no cycle.
+func ValidateNeeds(checks []Check) error {
+	_, err := resolveNeeds(checks)
+	return err
+}
+
+// resolveNeeds returns the indexes of the prerequisites of every check.
+func resolveNeeds(checks []Check) ([][]int, error) {
+	index := make(map[string]int, len(checks))
+	for i, check := range checks {
+		if _, ok := index[check.Name]; !ok {
+			index[check.Name] = i
+		}
+	}
+
+	needs := make([][]int, len(checks))
+	for i, check := range checks {
+		for _, name := range check.Needs {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("check %q needs unknown check %q", check.Name, name)
+			}
+			if j == i {
+				return nil, fmt.Errorf("check %q needs itself", check.Name)
+			}
+			needs[i] = append(needs[i], j)
+		}
+	}
+
+	if cycle := findCycle(needs); cycle != nil {
+		names := make([]string, len(cycle))
+		for i, j := range cycle {
+			names[i] = checks[j].Name
+		}
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(names, " -> "))
+	}
+
+	return needs, nil
+}
+
+// findCycle returns the indexes along a cycle in the graph, starting and
+// ending with the same index, or nil if the graph is acyclic.
+func findCycle(needs [][]int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(needs))
+	var stack []int
+
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range needs[i] {
+			switch state[j] {
+			case visiting:
+				for k, s := range stack {
+					if s == j {
+						return append(append([]int{}, stack[k:]...), j)
+					}
+				}
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range needs {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// ExecuteChecks runs checks as a dependency graph, with at most concurrency
+// checks at once (no limit when concurrency is 0), and returns a result for
+// every check in the order of checks. Ready checks start in the order given.
+// Checks whose prerequisites did not pass are skipped. When the stop policy
+// applies, such as after a blocking failure with exit code 2, the running checks
+// finish and those not started yet are skipped.
+func (e *Executor) ExecuteChecks(checks []Check, concurrency int) ([]Result, error) {
+	return e.ExecuteChecksContext(context.Background(), checks, concurrency)
+}
+
+// ExecuteChecksContext is like ExecuteChecks, but cancelling ctx cancels the
+// running checks and skips the rest. The OnStart and OnResult functions are
+// called from the calling goroutine, one at a time.
+func (e *Executor) ExecuteChecksContext(ctx context.Context, checks []Check, concurrency int) ([]Result, error) {
+	needs, err := resolveNeeds(checks)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.stream != nil {
+		names := make([]string, len(checks))
+		for i, check := range checks {
+			names[i] = resultTitle(newResult(check))
+		}
+		e.stream.Align(names)
+		e.stream.LimitLines(int(e.captureLimit))
+	}
+
+	type completion struct {
+		index  int
+		result Result
+	}
+	completions := make(chan completion, len(checks))
+
+	results := make([]Result, len(checks))
+	started := make([]bool, len(checks))
+	done := make([]bool, len(checks))
+	running, remaining := 0, len(checks)
+	stopReason := ""
+
+	finish := func(i int, result Result) {
+		results[i] = result
+		done[i] = true
+		remaining--
+		if e.onResult != nil {
+			e.onResult(result)
+		}
+	}
+
+	for remaining > 0 {
+		for progress := true; progress; {
+			progress = false
+			for i, check := range checks {
+				if started[i] {
+					continue
+				}
+
+				if stopReason == "" && ctx.Err() != nil {
+					stopReason = "run cancelled"
+				}
+				if stopReason != "" {
+					started[i] = true
+					finish(i, skippedResult(check, stopReason))
+					continue
+				}
+
+				ready, reason := prerequisitesDone(checks, needs[i], results, done)
+				switch {
+				case !ready:
+				case reason != "":
+					started[i] = true
+					finish(i, skippedResult(check, reason))
+					progress = true
+				case concurrency <= 0 || running < concurrency:
+					started[i] = true
+					running++
+					if e.onStart != nil {
+						e.onStart(check)
+					}
+					go func(i int, check Check) {
+						completions <- completion{index: i, result: e.runCheck(ctx, check)}
+					}(i, check)
+				}
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		c := <-completions
+		running--
+		finish(c.index, c.result)
+
+		if stopReason == "" {
+			// Running checks finish so that their results are reported.
+			stopReason = e.stopPolicy.stopReason(checks[c.index].Name, c.result)
+		}
+	}
+
+	var filterErrs []error
+	for _, result := range results {
+		filterErrs = e.collectFilterErrors(filterErrs, result)
+	}
+
+	return results, errors.Join(filterErrs...)
+}
+
+// StopPolicy decides when a run stops early. Once it applies, running checks
+// finish and the remaining checks are skipped.
+type StopPolicy struct {
+	// FailFast stops after the first blocking failure.
+	FailFast bool
+	// Codes stops after a blocking check exits with one of these codes.
+	Codes []int
+}
+
+// DefaultStopPolicy stops once a blocking check exits with code 2, which Claude
+// Code treats as a blocking error.
+var DefaultStopPolicy = StopPolicy{Codes: []int{2}}
+
+// stopReason returns why the run stops after the check named name finished with
+// result, or "" to keep going.
+func (p StopPolicy) stopReason(name string, result Result) string {
+	if !result.Blocking() {
+		return ""
+	}
+	if result.Status == StatusFailed && slices.Contains(p.Codes, result.ExitCode) {
+		return fmt.Sprintf("stopped after %q exited with code %d", name, result.ExitCode)
+	}
+	if p.FailFast {
+		return fmt.Sprintf("stopped after %q %s", name, statusVerb(result.Status))
+	}
+	return ""
+}
+
+// prerequisitesDone reports whether all prerequisites have finished and, if
+// one of them did not pass, why the check must be skipped.
+func prerequisitesDone(checks []Check, needs []int, results []Result, done []bool) (bool, string) {
+	for _, j := range needs {
+		if !done[j] {
+			return false, ""
+		}
+	}
+
+	for _, j := range needs {
+		switch results[j].Status {
+		case StatusPassed, StatusCached:
+		case StatusSkipped:
+			return true, fmt.Sprintf("needs %q, which was skipped", checks[j].Name)
+		default:
+			return true, fmt.Sprintf("needs %q, which %s", checks[j].Name, statusVerb(results[j].Status))
+		}
+	}
+
+	return true, ""
+}
+
+func statusVerb(status Status) string {
+	switch status {
+	case StatusTimedOut:
+		return "timed out"
+	case StatusCancelled:
+		return "was cancelled"
+	default:
+		return "failed"
+	}
+}
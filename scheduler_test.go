@@ -0,0 +1,203 @@
+package blocc_test
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shuntaka9576/blocc"
+	"github.com/shuntaka9576/blocc/blocctest"
+)
+
+func TestValidateNeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		checks  []blocc.Check
+		wantErr string
+	}{
+		{
+			name: "valid graph",
+			checks: []blocc.Check{
+				{Name: "gen"},
+				{Name: "build", Needs: []string{"gen"}},
+				{Name: "test", Needs: []string{"build", "gen"}},
+			},
+		},
+		{
+			name:    "unknown check",
+			checks:  []blocc.Check{{Name: "test", Needs: []string{"build"}}},
+			wantErr: `needs unknown check "build"`,
+		},
+		{name: "self", checks: []blocc.Check{{Name: "test", Needs: []string{"test"}}}, wantErr: "needs itself"},
+		{
+			name: "cycle",
+			checks: []blocc.Check{
+				{Name: "a", Needs: []string{"c"}},
+				{Name: "b", Needs: []string{"a"}},
+				{Name: "c", Needs: []string{"b"}},
+			},
+			wantErr: "dependency cycle: a -> c -> b -> a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := blocc.ValidateNeeds(tt.checks)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateNeeds() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateNeeds() error = %v, want contains %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteChecksNeeds(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 2} {
+		runner := blocctest.NewRunner().
+			On("make build").
+			On("make test").
+			On("make lint", blocctest.Response{ExitCode: 1}).
+			On("true")
+
+		// Listed before its prerequisite, test must only start after build.
+		checks := []blocc.Check{
+			{Name: "test", Command: "make test", Needs: []string{"build"}},
+			{Name: "build", Command: "make build"},
+			{Name: "lint", Command: "make lint"},
+			{Name: "vet", Command: "true", Needs: []string{"lint"}},
+			{Name: "report", Command: "true", Needs: []string{"vet", "test"}},
+		}
+
+		executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner)
+		results, err := executor.ExecuteChecks(checks, concurrency)
+		if err != nil {
+			t.Fatalf("ExecuteChecks(%d) error = %v", concurrency, err)
+		}
+
+		want := []struct {
+			status blocc.Status
+			reason string
+		}{
+			{blocc.StatusPassed, ""},
+			{blocc.StatusPassed, ""},
+			{blocc.StatusFailed, ""},
+			{blocc.StatusSkipped, `needs "lint", which failed`},
+			{blocc.StatusSkipped, `needs "vet", which was skipped`},
+		}
+
+		for i, w := range want {
+			if results[i].Name != checks[i].Name || results[i].Status != w.status || results[i].SkipReason != w.reason {
+				t.Errorf("ExecuteChecks(%d) result %d = %+v, want %s %q", concurrency, i, results[i], w.status, w.reason)
+			}
+		}
+
+		commands := runner.Commands()
+		build, test := slices.Index(commands, "make build"), slices.Index(commands, "make test")
+		if build < 0 || test < build {
+			t.Errorf("ExecuteChecks(%d) ran %q, want make test after make build", concurrency, commands)
+		}
+	}
+
+	invalid := []blocc.Check{{Name: "a", Needs: []string{"a"}}}
+	if _, err := blocc.NewExecutor(false, "", "", false).ExecuteChecks(invalid, 1); err == nil {
+		t.Error("ExecuteChecks() expected error for invalid needs")
+	}
+}
+
+func TestExecuteChecksStopPolicy(t *testing.T) {
+	checks := []blocc.Check{
+		{Name: "grep", Command: "./grep.sh", Severity: blocc.SeverityWarn},
+		{Name: "lint", Command: "make lint"},
+		{Name: "diff", Command: "./diff.sh"},
+		{Name: "test", Command: "make test"},
+	}
+
+	tests := []struct {
+		name   string
+		policy blocc.StopPolicy
+		want   []blocc.Status
+		ran    []string
+		reason string
+	}{
+		{
+			name:   "default stops on exit code 2",
+			policy: blocc.DefaultStopPolicy,
+			want:   []blocc.Status{blocc.StatusFailed, blocc.StatusFailed, blocc.StatusFailed, blocc.StatusSkipped},
+			ran:    []string{"./grep.sh", "make lint", "./diff.sh"},
+			reason: `stopped after "diff" exited with code 2`,
+		},
+		{
+			name:   "fail fast",
+			policy: blocc.StopPolicy{FailFast: true},
+			want:   []blocc.Status{blocc.StatusFailed, blocc.StatusFailed, blocc.StatusSkipped, blocc.StatusSkipped},
+			ran:    []string{"./grep.sh", "make lint"},
+			reason: `stopped after "lint" failed`,
+		},
+		{
+			name:   "custom codes",
+			policy: blocc.StopPolicy{Codes: []int{1}},
+			want:   []blocc.Status{blocc.StatusFailed, blocc.StatusFailed, blocc.StatusSkipped, blocc.StatusSkipped},
+			ran:    []string{"./grep.sh", "make lint"},
+			reason: `stopped after "lint" exited with code 1`,
+		},
+		{
+			name:   "continue always",
+			policy: blocc.StopPolicy{},
+			want:   []blocc.Status{blocc.StatusFailed, blocc.StatusFailed, blocc.StatusFailed, blocc.StatusPassed},
+			ran:    []string{"./grep.sh", "make lint", "./diff.sh", "make test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := blocctest.NewRunner().
+				On("./grep.sh", blocctest.Response{ExitCode: 2}).
+				On("make lint", blocctest.Response{ExitCode: 1}).
+				On("./diff.sh", blocctest.Response{ExitCode: 2}).
+				On("make test")
+			executor := blocc.NewExecutor(false, "", "", false).WithRunner(runner).WithStopPolicy(tt.policy)
+			results, _ := executor.ExecuteChecksSequential(checks)
+
+			for i, want := range tt.want {
+				if results[i].Status != want {
+					t.Errorf("result %d status = %s, want %s", i, results[i].Status, want)
+				}
+			}
+			if last := results[len(results)-1]; last.SkipReason != tt.reason {
+				t.Errorf("skip reason = %q, want %q", last.SkipReason, tt.reason)
+			}
+			if got := runner.Commands(); !reflect.DeepEqual(got, tt.ran) {
+				t.Errorf("ran %q, want %q", got, tt.ran)
+			}
+		})
+	}
+}
+
+func TestExecuteChecksContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	runner := blocctest.NewRunner().On("make slow", blocctest.Response{Block: true}).On("true")
+	checks := []blocc.Check{
+		{Name: "slow", Command: "make slow"},
+		{Name: "next", Command: "true"},
+	}
+
+	results, _ := blocc.NewExecutor(false, "", "", false).WithRunner(runner).ExecuteChecksContext(ctx, checks, 1)
+
+	slow, next := results[0], results[1]
+	if slow.Status != blocc.StatusCancelled || next.Status != blocc.StatusSkipped || next.SkipReason != "run cancelled" {
+		t.Errorf("ExecuteChecksContext() = %+v, want cancelled and skipped", results)
+	}
+	if calls := runner.Calls(); len(calls) != 1 || !calls[0].Killed {
+		t.Errorf("ExecuteChecksContext() ran %+v, want only the slow check, killed", calls)
+	}
+}
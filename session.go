@@ -0,0 +1,159 @@
+package blocc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// sessionTTL is how long the last run of a session is kept.
+const sessionTTL = 7 * 24 * time.Hour
+
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// SessionDiff compares the failures of a run with the previous run of the same session.
+type SessionDiff struct {
+	New       []string `json:"new,omitempty"`
+	Remaining []string `json:"remaining,omitempty"`
+	Fixed     []string `json:"fixed,omitempty"`
+}
+
+// DiffResults compares the blocking failures of previous and current, matching
+// results by check name.
+func DiffResults(previous, current []Result) SessionDiff {
+	before := make(map[string]bool)
+	for _, r := range FailedResults(previous) {
+		before[resultTitle(r)] = true
+	}
+
+	var diff SessionDiff
+	after := make(map[string]bool)
+	for _, r := range FailedResults(current) {
+		title := resultTitle(r)
+		after[title] = true
+		if before[title] {
+			diff.Remaining = append(diff.Remaining, title)
+		} else {
+			diff.New = append(diff.New, title)
+		}
+	}
+
+	for _, r := range FailedResults(previous) {
+		if title := resultTitle(r); !after[title] {
+			diff.Fixed = append(diff.Fixed, title)
+		}
+	}
+
+	return diff
+}
+
+// String returns counts such as "1 new, 2 remaining, 1 fixed".
+func (d SessionDiff) String() string {
+	return fmt.Sprintf("%d new, %d remaining, %d fixed", len(d.New), len(d.Remaining), len(d.Fixed))
+}
+
+// OmitUnchanged drops the output of failures whose output is identical to the
+// previous run and marks them as unchanged, so that repeated feedback stays small.
+func OmitUnchanged(previous, current []Result) []Result {
+	before := make(map[string]Result)
+	for _, r := range FailedResults(previous) {
+		before[resultTitle(r)] = r
+	}
+
+	results := make([]Result, len(current))
+	copy(results, current)
+	for i, r := range results {
+		prev, ok := before[resultTitle(r)]
+		if !ok || !r.Failed() || prev.ExitCode != r.ExitCode || prev.Stdout != r.Stdout || prev.Stderr != r.Stderr {
+			continue
+		}
+		if r.Stdout == "" && r.Stderr == "" {
+			continue
+		}
+		results[i].Stdout = ""
+		results[i].Stderr = ""
+		results[i].Unchanged = true
+	}
+
+	return results
+}
+
+// SessionStore keeps the results of the last run of each Claude Code session.
+type SessionStore struct {
+	Dir string
+}
+
+type sessionState struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	Results   []Result  `json:"results"`
+}
+
+// DefaultSessionStore returns a store in the sessions directory of the state directory.
+func DefaultSessionStore() (SessionStore, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return SessionStore{}, err
+	}
+	return SessionStore{Dir: filepath.Join(dir, "sessions")}, nil
+}
+
+func (s SessionStore) path(sessionID string) string {
+	return filepath.Join(s.Dir, unsafeSessionChars.ReplaceAllString(sessionID, "_")+".json")
+}
+
+// Load returns the results of the session's last run, or nil if there is none.
+func (s SessionStore) Load(sessionID string) ([]Result, error) {
+	data, err := os.ReadFile(s.path(sessionID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session state: %w", err)
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid session state %s: %w", s.path(sessionID), err)
+	}
+	return state.Results, nil
+}
+
+// Save replaces the session's last run and removes sessions not updated
+// within sessionTTL.
+func (s SessionStore) Save(sessionID string, results []Result) error {
+	data, err := json.Marshal(sessionState{UpdatedAt: time.Now(), Results: results})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session state: %w", err)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path(sessionID), data, 0600); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+
+	s.prune(time.Now().Add(-sessionTTL))
+	return nil
+}
+
+func (s SessionStore) prune(before time.Time) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(before) {
+			_ = os.Remove(filepath.Join(s.Dir, entry.Name()))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package blocc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffResults(t *testing.T) {
+	previous := []Result{
+		{Name: "lint", Status: StatusFailed, ExitCode: 1},
+		{Name: "test", Status: StatusFailed, ExitCode: 1},
+		{Name: "vet", Status: StatusPassed},
+	}
+	current := []Result{
+		{Name: "lint", Status: StatusPassed},
+		{Name: "test", Status: StatusFailed, ExitCode: 1},
+		{Name: "vet", Status: StatusTimedOut, ExitCode: 124},
+		{Name: "optional", Status: StatusFailed, ExitCode: 1, Severity: SeverityIgnore},
+	}
+
+	want := SessionDiff{New: []string{"vet"}, Remaining: []string{"test"}, Fixed: []string{"lint"}}
+	if got := DiffResults(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResults() = %+v, want %+v", got, want)
+	}
+
+	if got := want.String(); got != "1 new, 1 remaining, 1 fixed" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestOmitUnchanged(t *testing.T) {
+	previous := []Result{
+		{Name: "same", Status: StatusFailed, ExitCode: 1, Stderr: "error\n"},
+		{Name: "changed", Status: StatusFailed, ExitCode: 1, Stderr: "old\n"},
+	}
+	current := []Result{
+		{Name: "same", Status: StatusFailed, ExitCode: 1, Stderr: "error\n"},
+		{Name: "changed", Status: StatusFailed, ExitCode: 1, Stderr: "new\n"},
+		{Name: "new", Status: StatusFailed, ExitCode: 1, Stderr: "error\n"},
+	}
+
+	got := OmitUnchanged(previous, current)
+
+	if !got[0].Unchanged || got[0].Stderr != "" {
+		t.Errorf("unchanged failure = %+v, want output omitted", got[0])
+	}
+
+	for _, r := range got[1:] {
+		if r.Unchanged || r.Stderr == "" {
+			t.Errorf("result %q = %+v, want output kept", r.Name, r)
+		}
+	}
+
+	if current[0].Stderr != "error\n" {
+		t.Error("OmitUnchanged() modified its input")
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	store := SessionStore{Dir: filepath.Join(t.TempDir(), "sessions")}
+
+	results, err := store.Load("unknown")
+	if err != nil || results != nil {
+		t.Fatalf("Load() of unknown session = %v, %v", results, err)
+	}
+
+	saved := []Result{{Command: "false", Status: StatusFailed, ExitCode: 1, Stderr: "boom\n"}}
+	if err := store.Save("../escape/s1", saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.Dir, ".._escape_s1.json")); err != nil {
+		t.Errorf("session file not stored in the store directory: %v", err)
+	}
+
+	results, err = store.Load("../escape/s1")
+	if err != nil || !reflect.DeepEqual(results, saved) {
+		t.Errorf("Load() = %+v, %v, want %+v", results, err, saved)
+	}
+
+	stale := filepath.Join(store.Dir, "stale.json")
+	if err := os.WriteFile(stale, []byte(`{"results":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * sessionTTL)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Save("s2", nil); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale session was not pruned: %v", err)
+	}
+}
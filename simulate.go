@@ -0,0 +1,343 @@
+package blocc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Hook events that blocc can simulate.
+const (
+	EventStop             = "Stop"
+	EventSubagentStop     = "SubagentStop"
+	EventPreToolUse       = "PreToolUse"
+	EventPostToolUse      = "PostToolUse"
+	EventUserPromptSubmit = "UserPromptSubmit"
+)
+
+// DefaultHookTimeout is how long Claude Code lets a hook command run unless
+// the hook sets a timeout.
+const DefaultHookTimeout = 60 * time.Second
+
+// SimulationOptions describes the situation a simulated hook payload reports.
+type SimulationOptions struct {
+	SessionID      string
+	TranscriptPath string
+	Cwd            string
+	// StopHookActive reports that Claude is already continuing because a stop
+	// hook blocked, for Stop and SubagentStop.
+	StopHookActive bool
+	// ToolName and FilePath describe the tool call of PreToolUse and
+	// PostToolUse. ToolName defaults to Edit.
+	ToolName string
+	FilePath string
+	// Prompt is the prompt of UserPromptSubmit.
+	Prompt string
+}
+
+// SimulatePayload returns a hook payload for event like the one Claude Code
+// writes to the stdin of hook commands.
+func SimulatePayload(event string, opts SimulationOptions) ([]byte, error) {
+	transcriptPath := opts.TranscriptPath
+	if transcriptPath == "" {
+		transcriptPath = simulatedTranscriptPath(opts.Cwd, opts.SessionID)
+	}
+	payload := map[string]any{
+		"session_id":      opts.SessionID,
+		"transcript_path": transcriptPath,
+		"cwd":             opts.Cwd,
+		"hook_event_name": event,
+	}
+
+	switch event {
+	case EventStop, EventSubagentStop:
+		payload["stop_hook_active"] = opts.StopHookActive
+	case EventPreToolUse, EventPostToolUse:
+		toolName := opts.ToolName
+		if toolName == "" {
+			toolName = "Edit"
+		}
+		input, response, err := simulateToolCall(toolName, opts.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		payload["tool_name"] = toolName
+		payload["tool_input"] = input
+		if event == EventPostToolUse {
+			payload["tool_response"] = response
+		}
+	case EventUserPromptSubmit:
+		payload["prompt"] = opts.Prompt
+	default:
+		return nil, fmt.Errorf("unknown hook event %q", event)
+	}
+
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+// simulatedTranscriptPath returns where Claude Code keeps the transcript of
+// session in the project at cwd.
+func simulatedTranscriptPath(cwd, session string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	project := strings.NewReplacer("/", "-", ".", "-").Replace(cwd)
+	return filepath.Join(home, ".claude", "projects", project, session+".jsonl")
+}
+
+// simulateToolCall returns the tool_input and tool_response of a call to toolName.
+func simulateToolCall(toolName, filePath string) (input, response map[string]any, err error) {
+	switch toolName {
+	case "Edit", "MultiEdit", "Write":
+		if filePath == "" {
+			return nil, nil, fmt.Errorf("tool %s needs a file path", toolName)
+		}
+	}
+
+	switch toolName {
+	case "Edit":
+		input = map[string]any{"file_path": filePath, "old_string": "old", "new_string": "new"}
+		response = map[string]any{"filePath": filePath, "success": true}
+	case "MultiEdit":
+		input = map[string]any{
+			"file_path": filePath,
+			"edits":     []map[string]any{{"old_string": "old", "new_string": "new"}},
+		}
+		response = map[string]any{"filePath": filePath, "success": true}
+	case "Write":
+		content, _ := os.ReadFile(filePath)
+		input = map[string]any{"file_path": filePath, "content": string(content)}
+		response = map[string]any{"filePath": filePath, "success": true}
+	case "Bash":
+		input = map[string]any{"command": "true", "description": "Simulated command"}
+		response = map[string]any{"stdout": "", "stderr": "", "interrupted": false}
+	default:
+		return nil, nil, fmt.Errorf("cannot simulate tool %q (want Edit, MultiEdit, Write or Bash)", toolName)
+	}
+	return input, response, nil
+}
+
+// ConfiguredHook is a command hook found in Claude Code settings.
+type ConfiguredHook struct {
+	// Source is the settings file the hook comes from.
+	Source  string
+	Matcher string
+	Command string
+	Timeout time.Duration
+}
+
+// SettingsFiles returns the Claude Code settings files that apply to
+// projectDir, from lowest to highest precedence.
+func SettingsFiles(projectDir string) []string {
+	var files []string
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".claude", "settings.json"))
+	}
+	return append(files,
+		filepath.Join(projectDir, ".claude", "settings.json"),
+		filepath.Join(projectDir, ".claude", "settings.local.json"),
+	)
+}
+
+// FindHooks returns the command hooks of files that Claude Code would run for
+// event, and for a call to toolName with tool events. Missing files are skipped.
+func FindHooks(files []string, event, toolName string) ([]ConfiguredHook, error) {
+	var hooks []ConfiguredHook
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var settings struct {
+			Hooks map[string][]HookItem `json:"hooks"`
+		}
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("invalid settings file %s: %w", file, err)
+		}
+
+		for _, item := range settings.Hooks[event] {
+			if !matchesTool(item.Matcher, event, toolName) {
+				continue
+			}
+			for _, hook := range item.Hooks {
+				if hook.Type != "command" {
+					continue
+				}
+				timeout := DefaultHookTimeout
+				if hook.Timeout > 0 {
+					timeout = time.Duration(hook.Timeout) * time.Second
+				}
+				hooks = append(hooks, ConfiguredHook{
+					Source:  file,
+					Matcher: item.Matcher,
+					Command: hook.Command,
+					Timeout: timeout,
+				})
+			}
+		}
+	}
+	return hooks, nil
+}
+
+// matchesTool reports whether a hook matcher applies to a call to toolName.
+// Matchers only apply to tool events; an empty matcher or "*" matches every tool.
+func matchesTool(matcher, event, toolName string) bool {
+	if event != EventPreToolUse && event != EventPostToolUse {
+		return true
+	}
+	if matcher == "" || matcher == "*" {
+		return true
+	}
+	re, err := regexp.Compile("^(?:" + matcher + ")$")
+	return err == nil && re.MatchString(toolName)
+}
+
+// HookRun is the outcome of a hook command.
+type HookRun struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+	TimedOut bool
+}
+
+// RunHook runs a hook command the way Claude Code does: through sh in
+// projectDir, with CLAUDE_PROJECT_DIR set and the payload on stdin.
+func RunHook(ctx context.Context, hook ConfiguredHook, projectDir string, payload []byte) (HookRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	// #nosec G204 - Hook commands come from the user's own settings files
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Dir = projectDir
+	cmd.Env = append(os.Environ(), "CLAUDE_PROJECT_DIR="+projectDir)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.WaitDelay = processWaitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	run := HookRun{Stdout: stdout.String(), Stderr: stderr.String(), Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		run.TimedOut = true
+		run.ExitCode = -1
+	case errors.As(err, &exitErr):
+		run.ExitCode = exitErr.ExitCode()
+	case err != nil && !errors.Is(err, exec.ErrWaitDelay):
+		return run, err
+	}
+	return run, nil
+}
+
+// hookOutput is the JSON a hook may print on stdout when it exits with 0.
+type hookOutput struct {
+	Continue       *bool  `json:"continue"`
+	StopReason     string `json:"stopReason"`
+	SuppressOutput bool   `json:"suppressOutput"`
+	SystemMessage  string `json:"systemMessage"`
+	Decision       string `json:"decision"`
+	Reason         string `json:"reason"`
+}
+
+// InterpretHook explains how Claude Code handles the outcome of a hook for the
+// simulated event.
+func InterpretHook(event string, opts SimulationOptions, run HookRun) []string {
+	if run.TimedOut {
+		return []string{"The hook timed out: Claude Code reports a non-blocking error and carries on."}
+	}
+
+	switch run.ExitCode {
+	case 0:
+		return interpretSuccess(event, run.Stdout)
+	case 2:
+		lines := []string{interpretBlock(event)}
+		if (event == EventStop || event == EventSubagentStop) && opts.StopHookActive {
+			lines = append(lines, "stop_hook_active was true, so Claude already continued because of a stop hook: "+
+				"blocking again on every stop keeps Claude from ever stopping.")
+		}
+		if event != EventUserPromptSubmit && strings.TrimSpace(run.Stderr) == "" {
+			lines = append(lines, "Stderr is empty, so Claude is not told why.")
+		}
+		return lines
+	default:
+		return []string{fmt.Sprintf("Exit code %d is a non-blocking error: stderr is shown to the user, "+
+			"Claude does not see it and carries on.", run.ExitCode)}
+	}
+}
+
+func interpretSuccess(event, stdout string) []string {
+	var lines []string
+	var output hookOutput
+	trimmed := strings.TrimSpace(stdout)
+	isJSON := strings.HasPrefix(trimmed, "{") && json.Unmarshal([]byte(trimmed), &output) == nil
+
+	switch {
+	case isJSON && output.Continue != nil && !*output.Continue:
+		lines = append(lines, "\"continue\": false stops Claude entirely, showing the user: "+output.StopReason)
+	case isJSON && output.Decision == "block":
+		lines = append(lines, interpretDecisionBlock(event, output.Reason))
+	case event == EventStop || event == EventSubagentStop:
+		lines = append(lines, "Exit code 0: Claude is allowed to stop.")
+	case event == EventPreToolUse:
+		lines = append(lines, "Exit code 0: the tool call proceeds.")
+	default:
+		lines = append(lines, "Exit code 0: Claude carries on.")
+	}
+
+	switch {
+	case isJSON && output.SystemMessage != "":
+		lines = append(lines, "The user is shown the systemMessage: "+output.SystemMessage)
+	case isJSON:
+	case trimmed != "" && event == EventUserPromptSubmit:
+		lines = append(lines, "Stdout is added to the prompt as context for Claude.")
+	case trimmed != "":
+		lines = append(lines, "Stdout is only shown to the user in transcript mode (Ctrl+R).")
+	}
+	return lines
+}
+
+func interpretBlock(event string) string {
+	switch event {
+	case EventStop, EventSubagentStop:
+		return "Exit code 2 blocks stopping: stderr is fed to Claude, which keeps working on it."
+	case EventPreToolUse:
+		return "Exit code 2 blocks the tool call: stderr is fed to Claude."
+	case EventPostToolUse:
+		return "Exit code 2 after the tool ran: stderr is fed to Claude."
+	case EventUserPromptSubmit:
+		return "Exit code 2 blocks the prompt and erases it: stderr is shown to the user only."
+	default:
+		return "Exit code 2: stderr is fed to Claude."
+	}
+}
+
+func interpretDecisionBlock(event, reason string) string {
+	switch event {
+	case EventStop, EventSubagentStop:
+		return "\"decision\": \"block\" prevents stopping: Claude keeps working on the reason: " + reason
+	case EventUserPromptSubmit:
+		return "\"decision\": \"block\" blocks the prompt, showing the user: " + reason
+	default:
+		return "\"decision\": \"block\" feeds the reason to Claude: " + reason
+	}
+}
@@ -0,0 +1,241 @@
+package blocc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulatePayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		event   string
+		opts    SimulationOptions
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name:  "stop",
+			event: EventStop,
+			opts:  SimulationOptions{StopHookActive: true},
+			want:  map[string]any{"hook_event_name": "Stop", "stop_hook_active": true},
+		},
+		{
+			name:  "post tool use edit",
+			event: EventPostToolUse,
+			opts:  SimulationOptions{FilePath: "/app/main.go"},
+			want: map[string]any{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Edit",
+				"tool_input":      map[string]any{"file_path": "/app/main.go", "old_string": "old", "new_string": "new"},
+				"tool_response":   map[string]any{"filePath": "/app/main.go", "success": true},
+			},
+		},
+		{
+			name:  "pre tool use bash",
+			event: EventPreToolUse,
+			opts:  SimulationOptions{ToolName: "Bash"},
+			want: map[string]any{
+				"hook_event_name": "PreToolUse",
+				"tool_name":       "Bash",
+				"tool_input":      map[string]any{"command": "true", "description": "Simulated command"},
+			},
+		},
+		{
+			name:  "user prompt submit",
+			event: EventUserPromptSubmit,
+			opts:  SimulationOptions{Prompt: "hello"},
+			want:  map[string]any{"hook_event_name": "UserPromptSubmit", "prompt": "hello"},
+		},
+		{name: "edit without file", event: EventPostToolUse, wantErr: "needs a file path"},
+		{name: "unknown tool", event: EventPreToolUse, opts: SimulationOptions{ToolName: "Read"}, wantErr: "cannot simulate"},
+		{name: "unknown event", event: "Notification", wantErr: "unknown hook event"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.SessionID, tt.opts.Cwd, tt.opts.TranscriptPath = "s1", "/app", "/tmp/s1.jsonl"
+			data, err := SimulatePayload(tt.event, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SimulatePayload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			tt.want["session_id"], tt.want["cwd"], tt.want["transcript_path"] = "s1", "/app", "/tmp/s1.jsonl"
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SimulatePayload() = %v, want %v", got, tt.want)
+			}
+
+			payload, err := ReadHookPayload(bytes.NewReader(data))
+			if err != nil || payload.HookEventName != tt.event {
+				t.Errorf("ReadHookPayload() = %+v, %v", payload, err)
+			}
+		})
+	}
+}
+
+func TestFindHooks(t *testing.T) {
+	dir := t.TempDir()
+	settings := filepath.Join(dir, "settings.json")
+	data := `{"hooks": {
+		"Stop": [{"hooks": [{"type": "command", "command": "blocc make"}]}],
+		"PostToolUse": [
+			{"matcher": "Edit|Write", "hooks": [{"type": "command", "command": "blocc lint", "timeout": 5}]},
+			{"matcher": "*", "hooks": [{"type": "command", "command": "log"}, {"type": "prompt", "command": "x"}]}
+		]
+	}}`
+	if err := os.WriteFile(settings, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{filepath.Join(dir, "missing.json"), settings}
+
+	tests := []struct {
+		name     string
+		event    string
+		toolName string
+		want     []string
+	}{
+		{name: "stop", event: EventStop, want: []string{"blocc make"}},
+		{name: "matching tool", event: EventPostToolUse, toolName: "Write", want: []string{"blocc lint", "log"}},
+		{name: "other tool", event: EventPostToolUse, toolName: "Bash", want: []string{"log"}},
+		{name: "no hooks", event: EventUserPromptSubmit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := FindHooks(files, tt.event, tt.toolName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, hook := range hooks {
+				got = append(got, hook.Command)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindHooks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	hooks, _ := FindHooks(files, EventPostToolUse, "Edit")
+	if hooks[0].Timeout != 5*time.Second || hooks[1].Timeout != DefaultHookTimeout || hooks[0].Source != settings {
+		t.Errorf("FindHooks() = %+v, want timeouts of 5s and the default", hooks)
+	}
+}
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	hook := ConfiguredHook{Command: `cat; echo "$CLAUDE_PROJECT_DIR $(pwd)" >&2; exit 2`, Timeout: time.Minute}
+	run, err := RunHook(context.Background(), hook, dir, []byte(`{"hook_event_name":"Stop"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ExitCode != 2 || run.Stdout != `{"hook_event_name":"Stop"}` || run.Stderr != dir+" "+dir+"\n" {
+		t.Errorf("RunHook() = %+v", run)
+	}
+
+	hook = ConfiguredHook{Command: "sleep 10", Timeout: 50 * time.Millisecond}
+	run, err = RunHook(context.Background(), hook, dir, nil)
+	if err != nil || !run.TimedOut {
+		t.Errorf("RunHook() = %+v, %v, want timeout", run, err)
+	}
+}
+
+func TestInterpretHook(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		opts  SimulationOptions
+		run   HookRun
+		want  []string
+	}{
+		{name: "stop allowed", event: EventStop, want: []string{"Exit code 0: Claude is allowed to stop."}},
+		{
+			name:  "stop blocked",
+			event: EventStop,
+			run:   HookRun{ExitCode: 2, Stderr: "lint failed"},
+			want:  []string{"Exit code 2 blocks stopping: stderr is fed to Claude, which keeps working on it."},
+		},
+		{
+			name:  "stop blocked again",
+			event: EventStop,
+			opts:  SimulationOptions{StopHookActive: true},
+			run:   HookRun{ExitCode: 2},
+			want: []string{
+				"Exit code 2 blocks stopping: stderr is fed to Claude, which keeps working on it.",
+				"stop_hook_active was true, so Claude already continued because of a stop hook: " +
+					"blocking again on every stop keeps Claude from ever stopping.",
+				"Stderr is empty, so Claude is not told why.",
+			},
+		},
+		{
+			name:  "pre tool use blocked",
+			event: EventPreToolUse,
+			run:   HookRun{ExitCode: 2, Stderr: "no"},
+			want:  []string{"Exit code 2 blocks the tool call: stderr is fed to Claude."},
+		},
+		{
+			name:  "non-blocking error",
+			event: EventPostToolUse,
+			run:   HookRun{ExitCode: 1},
+			want: []string{"Exit code 1 is a non-blocking error: stderr is shown to the user, " +
+				"Claude does not see it and carries on."},
+		},
+		{
+			name:  "system message",
+			event: EventStop,
+			run:   HookRun{Stdout: `{"systemMessage":"all checks passed"}`},
+			want: []string{
+				"Exit code 0: Claude is allowed to stop.",
+				"The user is shown the systemMessage: all checks passed",
+			},
+		},
+		{
+			name:  "decision block",
+			event: EventStop,
+			run:   HookRun{Stdout: `{"decision":"block","reason":"tests fail"}`},
+			want:  []string{`"decision": "block" prevents stopping: Claude keeps working on the reason: tests fail`},
+		},
+		{
+			name:  "continue false",
+			event: EventPostToolUse,
+			run:   HookRun{Stdout: `{"continue":false,"stopReason":"done"}`},
+			want:  []string{`"continue": false stops Claude entirely, showing the user: done`},
+		},
+		{
+			name:  "prompt context",
+			event: EventUserPromptSubmit,
+			run:   HookRun{Stdout: "branch: main\n"},
+			want:  []string{"Exit code 0: Claude carries on.", "Stdout is added to the prompt as context for Claude."},
+		},
+		{
+			name:  "timed out",
+			event: EventStop,
+			run:   HookRun{ExitCode: -1, TimedOut: true},
+			want:  []string{"The hook timed out: Claude Code reports a non-blocking error and carries on."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InterpretHook(tt.event, tt.opts, tt.run); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InterpretHook() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
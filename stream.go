@@ -0,0 +1,99 @@
+package blocc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Streamer writes the output of commands to w while they run, one whole line
+// at a time and prefixed with the command's name, so that the output of
+// parallel commands interleaves line by line.
+type Streamer struct {
+	mu        sync.Mutex
+	w         io.Writer
+	width     int
+	lineLimit int
+}
+
+// NewStreamer returns a streamer writing to w, keeping lines within
+// DefaultCaptureLimit bytes.
+func NewStreamer(w io.Writer) *Streamer {
+	return &Streamer{w: w, lineLimit: DefaultCaptureLimit}
+}
+
+// LimitLines keeps at most limit bytes of each line in memory. The rest of a
+// longer line is dropped and the line is marked as truncated.
+func (s *Streamer) LimitLines(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lineLimit = max(limit, 1)
+}
+
+// Align pads prefixes to the longest of names.
+func (s *Streamer) Align(names []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		s.width = max(s.width, len(name))
+	}
+}
+
+// Writer returns a writer for one output stream of the command name. Close
+// writes a final line without a trailing newline.
+func (s *Streamer) Writer(name string) io.WriteCloser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &lineWriter{streamer: s, name: name, limit: s.lineLimit}
+}
+
+func (s *Streamer) writeLine(name string, line []byte, dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dropped > 0 {
+		_, _ = fmt.Fprintf(s.w, "%-*s | %s ... [truncated %d bytes]\n", s.width, name, line, dropped)
+		return
+	}
+	_, _ = fmt.Fprintf(s.w, "%-*s | %s\n", s.width, name, line)
+}
+
+type lineWriter struct {
+	streamer *Streamer
+	name     string
+	limit    int
+	// partial is the start of the current line, up to limit bytes; dropped
+	// counts the bytes of the line beyond that.
+	partial []byte
+	dropped int
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.keep(p)
+			return n, nil
+		}
+		w.keep(p[:i])
+		w.streamer.writeLine(w.name, w.partial, w.dropped)
+		w.partial, w.dropped = w.partial[:0], 0
+		p = p[i+1:]
+	}
+}
+
+// keep adds b to the current line within the limit.
+func (w *lineWriter) keep(b []byte) {
+	k := max(0, min(len(b), w.limit-len(w.partial)))
+	w.partial = append(w.partial, b[:k]...)
+	w.dropped += len(b) - k
+}
+
+func (w *lineWriter) Close() error {
+	if len(w.partial) > 0 || w.dropped > 0 {
+		w.streamer.writeLine(w.name, w.partial, w.dropped)
+		w.partial, w.dropped = nil, 0
+	}
+	return nil
+}
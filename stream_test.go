@@ -0,0 +1,105 @@
+package blocc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStreamer(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStreamer(&buf)
+	s.Align([]string{"lint", "test"})
+	s.Align([]string{"typecheck"})
+
+	lint, test := s.Writer("lint"), s.Writer("test")
+	writes := []struct {
+		w    interface{ Write([]byte) (int, error) }
+		data string
+	}{
+		{lint, "first "},
+		{test, "ok\nrunning"},
+		{lint, "line\nsecond line\n"},
+		{test, " suite\n\n"},
+		{lint, "no newline"},
+	}
+	for _, w := range writes {
+		if n, err := w.w.Write([]byte(w.data)); err != nil || n != len(w.data) {
+			t.Fatalf("Write(%q) = %d, %v", w.data, n, err)
+		}
+	}
+	if err := lint.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test      | ok\n" +
+		"lint      | first line\n" +
+		"lint      | second line\n" +
+		"test      | running suite\n" +
+		"test      | \n" +
+		"lint      | no newline\n"
+	if got := buf.String(); got != want {
+		t.Errorf("stream output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStreamerLineLimit(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStreamer(&buf)
+	s.LimitLines(8)
+
+	w := s.Writer("build")
+	for _, data := range []string{"short\n0123", "456789abc\nnext", " line without newline"} {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "build | short\n" +
+		"build | 01234567 ... [truncated 5 bytes]\n" +
+		"build | next lin ... [truncated 17 bytes]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("stream output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStreamerConcurrentWriters(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewStreamer(&buf)
+
+	const writers, lines = 8, 200
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := s.Writer(fmt.Sprintf("w%d", i))
+			defer w.Close()
+			for j := range lines {
+				// Split every line across two writes.
+				fmt.Fprintf(w, "line %d of ", j)
+				fmt.Fprintf(w, "w%d\n", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != writers*lines {
+		t.Fatalf("got %d lines, want %d", len(got), writers*lines)
+	}
+	for _, line := range got {
+		name, text, ok := strings.Cut(line, " | ")
+		if !ok || !strings.HasSuffix(text, " of "+name) {
+			t.Fatalf("interleaved line %q", line)
+		}
+	}
+}
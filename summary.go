@@ -0,0 +1,101 @@
+package blocc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration encoded in JSON as a string such as "1.25s".
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).Round(time.Millisecond).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// Summary counts the results of a run by status.
+type Summary struct {
+	Total     int      `json:"total"`
+	Passed    int      `json:"passed"`
+	Failed    int      `json:"failed"`
+	Warned    int      `json:"warned,omitempty"`
+	TimedOut  int      `json:"timedOut,omitempty"`
+	Cancelled int      `json:"cancelled,omitempty"`
+	Skipped   int      `json:"skipped,omitempty"`
+	Cached    int      `json:"cached,omitempty"`
+	Duration  Duration `json:"duration"`
+}
+
+// NewSummary summarizes results of a run that took duration.
+func NewSummary(results []Result, duration time.Duration) Summary {
+	summary := Summary{Total: len(results), Duration: Duration(duration)}
+
+	for _, r := range results {
+		if r.Failed() && r.Severity == SeverityWarn {
+			summary.Warned++
+			continue
+		}
+		switch r.Status {
+		case StatusPassed:
+			summary.Passed++
+		case StatusFailed:
+			summary.Failed++
+		case StatusTimedOut:
+			summary.TimedOut++
+		case StatusCancelled:
+			summary.Cancelled++
+		case StatusSkipped:
+			summary.Skipped++
+		case StatusCached:
+			summary.Cached++
+		}
+	}
+
+	return summary
+}
+
+// String returns a one-line description such as "3 passed, 1 failed in 2.5s".
+func (s Summary) String() string {
+	var parts []string
+	for _, c := range []struct {
+		count int
+		label string
+	}{
+		{s.Passed, "passed"},
+		{s.Failed, "failed"},
+		{s.Warned, "warned"},
+		{s.TimedOut, "timed out"},
+		{s.Cancelled, "cancelled"},
+		{s.Skipped, "skipped"},
+		{s.Cached, "cached"},
+	} {
+		if c.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.count, c.label))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "no checks in " + s.Duration.String()
+	}
+	return strings.Join(parts, ", ") + " in " + s.Duration.String()
+}
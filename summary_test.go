@@ -0,0 +1,71 @@
+package blocc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationJSON(t *testing.T) {
+	data, err := json.Marshal(Duration(1234567 * time.Microsecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `"1.235s"` {
+		t.Errorf("Marshal() = %s, want %q", data, "1.235s")
+	}
+
+	var d Duration
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if time.Duration(d) != 1235*time.Millisecond {
+		t.Errorf("Unmarshal() = %v, want 1.235s", time.Duration(d))
+	}
+
+	if err := json.Unmarshal([]byte(`"soon"`), &d); err == nil {
+		t.Error("Unmarshal() expected error for invalid duration")
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	results := []Result{
+		{Status: StatusPassed},
+		{Status: StatusPassed},
+		{Status: StatusFailed},
+		{Status: StatusFailed, Severity: SeverityWarn},
+		{Status: StatusTimedOut},
+		{Status: StatusCancelled},
+		{Status: StatusSkipped},
+	}
+
+	want := Summary{
+		Total: 7, Passed: 2, Failed: 1, Warned: 1, TimedOut: 1, Cancelled: 1, Skipped: 1,
+		Duration: Duration(time.Second),
+	}
+	if got := NewSummary(results, time.Second); got != want {
+		t.Errorf("NewSummary() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryString(t *testing.T) {
+	tests := []struct {
+		summary Summary
+		want    string
+	}{
+		{summary: Summary{Total: 2, Passed: 2, Duration: Duration(1500 * time.Millisecond)}, want: "2 passed in 1.5s"},
+		{
+			summary: Summary{Total: 4, Passed: 1, Failed: 1, TimedOut: 1, Skipped: 1, Duration: Duration(time.Second)},
+			want:    "1 passed, 1 failed, 1 timed out, 1 skipped in 1s",
+		},
+		{summary: Summary{}, want: "no checks in 0s"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.summary.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
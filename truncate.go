@@ -0,0 +1,221 @@
+package blocc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// bytesPerToken is the rough ratio used to convert a token budget into bytes.
+const bytesPerToken = 4
+
+type TruncateStrategy string
+
+const (
+	TruncateHeadTail TruncateStrategy = "head-tail"
+	TruncateHead     TruncateStrategy = "head"
+	TruncateTail     TruncateStrategy = "tail"
+	TruncateErrors   TruncateStrategy = "errors"
+)
+
+// DefaultErrorPattern matches lines that are worth keeping with the errors strategy.
+var DefaultErrorPattern = regexp.MustCompile(
+	`(?i)(error|fail|panic|fatal|exception|warning|undefined|cannot|expected|:\d+:\d+)`,
+)
+
+type TruncateOptions struct {
+	MaxBytes     int
+	Strategy     TruncateStrategy
+	ErrorPattern *regexp.Regexp
+}
+
+var budgetPattern = regexp.MustCompile(`^(\d+)\s*([a-z]*)$`)
+
+// ParseOutputBudget converts a budget such as "8000", "32kb" or "5000tokens" into bytes.
+func ParseOutputBudget(s string) (int, error) {
+	m := budgetPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid output budget %q", s)
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid output budget %q: %w", s, err)
+	}
+
+	switch m[2] {
+	case "", "b", "bytes":
+		return n, nil
+	case "k", "kb":
+		return n * 1024, nil
+	case "m", "mb":
+		return n * 1024 * 1024, nil
+	case "t", "tokens":
+		return n * bytesPerToken, nil
+	default:
+		return 0, fmt.Errorf("invalid output budget unit %q", m[2])
+	}
+}
+
+func ParseTruncateStrategy(s string) (TruncateStrategy, error) {
+	switch strategy := TruncateStrategy(s); strategy {
+	case TruncateHeadTail, TruncateHead, TruncateTail, TruncateErrors:
+		return strategy, nil
+	case "":
+		return TruncateHeadTail, nil
+	default:
+		return "", fmt.Errorf("unknown truncate strategy %q", s)
+	}
+}
+
+// TruncateResults shrinks the stdout and stderr of all results so that together
+// they fit in opts.MaxBytes. Small outputs are kept intact and the remaining
+// budget is shared evenly among the larger ones.
+func TruncateResults(results []Result, opts TruncateOptions) []Result {
+	if opts.MaxBytes <= 0 {
+		return results
+	}
+
+	truncated := make([]Result, len(results))
+	copy(truncated, results)
+
+	var streams []*string
+	for i := range truncated {
+		if truncated[i].Stderr != "" {
+			streams = append(streams, &truncated[i].Stderr)
+		}
+		if truncated[i].Stdout != "" {
+			streams = append(streams, &truncated[i].Stdout)
+		}
+	}
+
+	limits := allocateBudget(streams, opts.MaxBytes)
+
+	for i := range truncated {
+		r := &truncated[i]
+		if limit, ok := limits[&r.Stderr]; ok && len(r.Stderr) > limit {
+			r.OriginalStderrBytes = len(r.Stderr)
+			r.Stderr = truncateText(r.Stderr, limit, opts)
+			r.Truncated = true
+		}
+		if limit, ok := limits[&r.Stdout]; ok && len(r.Stdout) > limit {
+			r.OriginalStdoutBytes = len(r.Stdout)
+			r.Stdout = truncateText(r.Stdout, limit, opts)
+			r.Truncated = true
+		}
+	}
+
+	return truncated
+}
+
+func allocateBudget(streams []*string, budget int) map[*string]int {
+	sorted := make([]*string, len(streams))
+	copy(sorted, streams)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(*sorted[i]) < len(*sorted[j])
+	})
+
+	limits := make(map[*string]int, len(sorted))
+	remaining := budget
+	for i, s := range sorted {
+		share := remaining / (len(sorted) - i)
+		limit := min(len(*s), share)
+		limits[s] = limit
+		remaining -= limit
+	}
+
+	return limits
+}
+
+func truncateText(s string, limit int, opts TruncateOptions) string {
+	if len(s) <= limit {
+		return s
+	}
+
+	switch opts.Strategy {
+	case TruncateHead:
+		head := cutHead(s, limit)
+		return head + truncationMarker(len(s)-len(head))
+	case TruncateTail:
+		tail := cutTail(s, limit)
+		return truncationMarker(len(s)-len(tail)) + tail
+	case TruncateErrors:
+		if kept, ok := keepErrorLines(s, limit, opts.ErrorPattern); ok {
+			return kept
+		}
+	}
+
+	head := cutHead(s, limit/2)
+	tail := cutTail(s[len(head):], limit-len(head))
+	return head + truncationMarker(len(s)-len(head)-len(tail)) + tail
+}
+
+func keepErrorLines(s string, limit int, pattern *regexp.Regexp) (string, bool) {
+	if pattern == nil {
+		pattern = DefaultErrorPattern
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if !pattern.MatchString(line) {
+			continue
+		}
+		if b.Len()+len(line) > limit {
+			break
+		}
+		b.WriteString(line)
+	}
+
+	if b.Len() == 0 {
+		return "", false
+	}
+
+	kept := b.String()
+	return kept + fmt.Sprintf("\n... [truncated %d bytes, kept lines matching error patterns] ...\n",
+		len(s)-len(kept)), true
+}
+
+func truncationMarker(omitted int) string {
+	return fmt.Sprintf("\n... [truncated %d bytes] ...\n", omitted)
+}
+
+// cutHead returns a prefix of s no longer than n bytes, preferring to end on a line break.
+func cutHead(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	head := s[:n]
+	if i := strings.LastIndexByte(head, '\n'); i > n/2 {
+		return head[:i+1]
+	}
+	// Back off to the start of a character cut in two. Bytes that cannot be
+	// part of one are kept as they are.
+	for i := n; i > 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s[i]) {
+			return s[:i]
+		}
+	}
+	return head
+}
+
+// cutTail returns a suffix of s no longer than n bytes, preferring to start on a new line.
+func cutTail(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	start := len(s) - n
+	tail := s[start:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < n/2 {
+		return tail[i+1:]
+	}
+	// Skip the rest of a character cut in two.
+	for i := start; i < len(s) && i < start+utf8.UTFMax; i++ {
+		if utf8.RuneStart(s[i]) {
+			return s[i:]
+		}
+	}
+	return tail
+}
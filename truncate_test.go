@@ -0,0 +1,196 @@
+package blocc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOutputBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "plain bytes", input: "8000", want: 8000},
+		{name: "kilobytes", input: "32kb", want: 32 * 1024},
+		{name: "megabytes upper case", input: "1MB", want: 1024 * 1024},
+		{name: "tokens", input: "5000tokens", want: 5000 * bytesPerToken},
+		{name: "tokens short", input: "100t", want: 100 * bytesPerToken},
+		{name: "unknown unit", input: "10gb", wantErr: true},
+		{name: "not a number", input: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutputBudget(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOutputBudget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseOutputBudget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := range n {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat("x", 10))
+		b.WriteString(" ")
+		b.WriteByte(byte('a' + i%26))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestTruncateResults(t *testing.T) {
+	large := numberedLines(1000)
+
+	tests := []struct {
+		name     string
+		results  []Result
+		opts     TruncateOptions
+		validate func(t *testing.T, results []Result)
+	}{
+		{
+			name:    "within budget is untouched",
+			results: []Result{{Command: "a", ExitCode: 1, Stderr: "small"}},
+			opts:    TruncateOptions{MaxBytes: 100, Strategy: TruncateHeadTail},
+			validate: func(t *testing.T, results []Result) {
+				if results[0].Truncated || results[0].Stderr != "small" {
+					t.Errorf("unexpected truncation: %+v", results[0])
+				}
+			},
+		},
+		{
+			name:    "head-tail keeps both ends",
+			results: []Result{{Command: "a", ExitCode: 1, Stderr: "first\n" + large + "last\n"}},
+			opts:    TruncateOptions{MaxBytes: 200, Strategy: TruncateHeadTail},
+			validate: func(t *testing.T, results []Result) {
+				r := results[0]
+				if !r.Truncated {
+					t.Fatal("expected result to be truncated")
+				}
+				if !strings.HasPrefix(r.Stderr, "first\n") || !strings.HasSuffix(r.Stderr, "last\n") {
+					t.Errorf("head or tail missing: %q", r.Stderr)
+				}
+				if !strings.Contains(r.Stderr, "[truncated") {
+					t.Errorf("missing truncation marker: %q", r.Stderr)
+				}
+				if r.OriginalStderrBytes != len("first\n"+large+"last\n") {
+					t.Errorf("OriginalStderrBytes = %d", r.OriginalStderrBytes)
+				}
+			},
+		},
+		{
+			name:    "errors strategy keeps matching lines",
+			results: []Result{{Command: "a", ExitCode: 1, Stdout: large + "main.go:10:2: undefined: foo\n" + large}},
+			opts:    TruncateOptions{MaxBytes: 300, Strategy: TruncateErrors},
+			validate: func(t *testing.T, results []Result) {
+				r := results[0]
+				if !strings.HasPrefix(r.Stdout, "main.go:10:2: undefined: foo\n") {
+					t.Errorf("error line not kept: %q", r.Stdout)
+				}
+				if r.OriginalStdoutBytes == 0 {
+					t.Error("expected OriginalStdoutBytes to be recorded")
+				}
+			},
+		},
+		{
+			name: "budget shared across results",
+			results: []Result{
+				{Command: "small", ExitCode: 1, Stderr: "tiny error\n"},
+				{Command: "big1", ExitCode: 1, Stderr: large},
+				{Command: "big2", ExitCode: 1, Stderr: large},
+			},
+			opts: TruncateOptions{MaxBytes: 1000, Strategy: TruncateHead},
+			validate: func(t *testing.T, results []Result) {
+				if results[0].Truncated {
+					t.Error("small output should not be truncated")
+				}
+				total := 0
+				for _, r := range results {
+					total += len(r.Stderr)
+				}
+				// Allow room for the truncation markers.
+				if total > 1000+2*64 {
+					t.Errorf("total output %d exceeds budget", total)
+				}
+				if !results[1].Truncated || !results[2].Truncated {
+					t.Error("large outputs should be truncated")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := make([]Result, len(tt.results))
+			copy(original, tt.results)
+
+			got := TruncateResults(tt.results, tt.opts)
+			tt.validate(t, got)
+
+			for i := range original {
+				if !reflect.DeepEqual(original[i], tt.results[i]) {
+					t.Errorf("input result %d was modified", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCutHeadAndTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		n        int
+		wantHead string
+		wantTail string
+	}{
+		{name: "ascii", s: "abcdef", n: 3, wantHead: "abc", wantTail: "def"},
+		{name: "multi-byte character not split", s: "aé日b", n: 3, wantHead: "aé", wantTail: "b"},
+		{name: "whole characters kept", s: "日本語", n: 6, wantHead: "日本", wantTail: "本語"},
+		{name: "invalid bytes kept", s: "\xff\xff\xff\xff\xff\xff", n: 3, wantHead: "\xff\xff\xff", wantTail: "\xff\xff\xff"},
+		{
+			name:     "invalid byte before the cut",
+			s:        "ab\xffcdefgh",
+			n:        5,
+			wantHead: "ab\xffcd",
+			wantTail: "defgh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cutHead(tt.s, tt.n); got != tt.wantHead {
+				t.Errorf("cutHead(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.wantHead)
+			}
+			if got := cutTail(tt.s, tt.n); got != tt.wantTail {
+				t.Errorf("cutTail(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.wantTail)
+			}
+		})
+	}
+}
+
+func TestTruncateInvalidUTF8(t *testing.T) {
+	// One invalid byte in a large output must neither make truncation slow nor
+	// drop the output around it.
+	s := strings.Repeat("a", 1<<20) + "\xff" + strings.Repeat("b", 6<<20)
+	c := NewCapture(64<<10, 0)
+	_, _ = c.Write([]byte(s))
+	if got := c.String(); !strings.HasPrefix(got, strings.Repeat("a", 32<<10)) ||
+		!strings.HasSuffix(got, strings.Repeat("b", 32<<10)) {
+		t.Errorf("Capture.String() = %.50q ... %q", got, got[max(0, len(got)-50):])
+	}
+
+	garbage := NewCapture(64<<10, 0)
+	_, _ = garbage.Write([]byte(strings.Repeat("\xff", 6<<20)))
+	if got := garbage.String(); len(got) < 64<<10 {
+		t.Errorf("Capture.String() of invalid bytes kept %d bytes, want the head and tail", len(got))
+	}
+}
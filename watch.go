@@ -0,0 +1,152 @@
+package blocc
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Default polling settings of a Watcher.
+const (
+	DefaultWatchInterval = 500 * time.Millisecond
+	DefaultWatchDebounce = 300 * time.Millisecond
+)
+
+// Watcher polls a directory tree for changed files. The .git directory, files
+// ignored by .gitignore files and Exclude are not watched.
+type Watcher struct {
+	Root     string
+	Interval time.Duration
+	// Debounce is how long files must stay unchanged before changes are reported.
+	Debounce time.Duration
+	// Exclude lists absolute paths that are never reported, such as report files.
+	Exclude []string
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher returns a watcher for root with the default interval and debounce.
+func NewWatcher(root string) *Watcher {
+	return &Watcher{Root: root, Interval: DefaultWatchInterval, Debounce: DefaultWatchDebounce}
+}
+
+// Watch sends the sorted slash-separated paths, relative to Root, of the files
+// created, modified or removed since the previous batch, once they have not
+// changed for Debounce. It returns when ctx is done, or with the first error.
+func (w *Watcher) Watch(ctx context.Context, changes chan<- []string) error {
+	previous, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	pending := make(map[string]bool)
+	var lastChange time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := w.snapshot()
+		if err != nil {
+			return err
+		}
+		if changed := diffSnapshots(previous, current); len(changed) > 0 {
+			for _, name := range changed {
+				pending[name] = true
+			}
+			lastChange = time.Now()
+		}
+		previous = current
+
+		if len(pending) == 0 || time.Since(lastChange) < w.Debounce {
+			continue
+		}
+
+		batch := make([]string, 0, len(pending))
+		for name := range pending {
+			batch = append(batch, name)
+		}
+		sort.Strings(batch)
+		clear(pending)
+
+		select {
+		case changes <- batch:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// snapshot records the modification time and size of every watched file.
+func (w *Watcher) snapshot() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	var ignore GitIgnore
+
+	err := filepath.WalkDir(w.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may disappear while walking.
+			if p != w.Root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		rel, err := filepath.Rel(w.Root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || (rel != "." && ignore.Ignored(rel, true)) || slices.Contains(w.Exclude, p) {
+				return filepath.SkipDir
+			}
+			return ignore.AddFile(w.Root, rel)
+		}
+		if ignore.Ignored(rel, false) || slices.Contains(w.Exclude, p) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files[rel] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	return files, err
+}
+
+// diffSnapshots returns the paths created, modified or removed between two snapshots.
+func diffSnapshots(previous, current map[string]fileState) []string {
+	var changed []string
+	for name, state := range current {
+		if before, ok := previous[name]; !ok || before.size != state.size || !before.modTime.Equal(state.modTime) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
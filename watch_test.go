@@ -0,0 +1,82 @@
+package blocc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcherWatch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		".gitignore":  "*.log\nout/\n",
+		"main.go":     "package main\n",
+		"old.go":      "package main\n",
+		".git/HEAD":   "",
+		"report.json": "",
+	})
+
+	watcher := NewWatcher(root)
+	watcher.Interval = 10 * time.Millisecond
+	watcher.Debounce = 50 * time.Millisecond
+	watcher.Exclude = []string{filepath.Join(root, "report.json")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan []string)
+	errc := make(chan error, 1)
+	go func() { errc <- watcher.Watch(ctx, changes) }()
+
+	// Let the watcher take its first snapshot.
+	time.Sleep(50 * time.Millisecond)
+	writeFiles(t, root, map[string]string{
+		"main.go":     "package main // changed\n",
+		"new.go":      "package main\n",
+		"debug.log":   "ignored",
+		"out/bin":     "ignored",
+		".git/index":  "ignored",
+		"report.json": "excluded",
+	})
+	if err := os.Remove(filepath.Join(root, "old.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-changes:
+		if want := []string{"main.go", "new.go", "old.go"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Watch() = %v, want %v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() reported no changes")
+	}
+
+	cancel()
+	if err := <-errc; err != nil {
+		t.Errorf("Watch() error = %v", err)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"same":     {modTime: now, size: 1},
+		"modified": {modTime: now, size: 1},
+		"resized":  {modTime: now, size: 1},
+		"removed":  {modTime: now, size: 1},
+	}
+	current := map[string]fileState{
+		"same":     {modTime: now, size: 1},
+		"modified": {modTime: now.Add(time.Second), size: 1},
+		"resized":  {modTime: now, size: 2},
+		"added":    {modTime: now, size: 1},
+	}
+
+	want := []string{"added", "modified", "removed", "resized"}
+	if got := diffSnapshots(previous, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffSnapshots() = %v, want %v", got, want)
+	}
+}
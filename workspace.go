@@ -0,0 +1,434 @@
+package blocc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WorkspaceKind is the ecosystem a workspace package belongs to.
+type WorkspaceKind string
+
+const (
+	WorkspaceGo    WorkspaceKind = "go"
+	WorkspaceNode  WorkspaceKind = "node"
+	WorkspaceCargo WorkspaceKind = "cargo"
+)
+
+// Workspace is a package of a monorepo.
+type Workspace struct {
+	// Dir is the slash-separated package directory relative to the repository root.
+	Dir  string
+	Kind WorkspaceKind
+}
+
+// DiscoverWorkspaces finds the packages declared in go.work, the workspaces of
+// package.json or pnpm-workspace.yaml, and the members of the Cargo.toml
+// workspace in root. Packages are sorted by directory.
+func DiscoverWorkspaces(root string) ([]Workspace, error) {
+	var workspaces []Workspace
+	seen := make(map[Workspace]bool)
+
+	add := func(kind WorkspaceKind, dirs []string) {
+		for _, dir := range dirs {
+			ws := Workspace{Dir: dir, Kind: kind}
+			if !seen[ws] {
+				seen[ws] = true
+				workspaces = append(workspaces, ws)
+			}
+		}
+	}
+
+	for _, source := range []struct {
+		kind     WorkspaceKind
+		file     string
+		discover func(root string, content []byte) ([]string, error)
+	}{
+		{WorkspaceGo, "go.work", goWorkspaces},
+		{WorkspaceNode, "package.json", npmWorkspaces},
+		{WorkspaceNode, "pnpm-workspace.yaml", pnpmWorkspaces},
+		{WorkspaceCargo, "Cargo.toml", cargoWorkspaces},
+	} {
+		content, err := os.ReadFile(filepath.Join(root, source.file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workspaces: %w", err)
+		}
+
+		dirs, err := source.discover(root, content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.file, err)
+		}
+		add(source.kind, dirs)
+	}
+
+	sort.SliceStable(workspaces, func(i, j int) bool { return workspaces[i].Dir < workspaces[j].Dir })
+	return workspaces, nil
+}
+
+// goWorkspaces returns the directories of the use directives in a go.work file.
+func goWorkspaces(_ string, content []byte) ([]string, error) {
+	var dirs []string
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+		case inBlock && line != "":
+			dirs = append(dirs, unquote(line))
+		case line == "use (" || line == "use(":
+			inBlock = true
+		case strings.HasPrefix(line, "use "):
+			dirs = append(dirs, unquote(strings.TrimSpace(strings.TrimPrefix(line, "use "))))
+		}
+	}
+
+	for i, dir := range dirs {
+		dirs[i] = cleanWorkspaceDir(dir)
+	}
+	return dirs, scanner.Err()
+}
+
+// npmWorkspaces returns the package directories matching the workspaces of a
+// package.json file, given either as an array or as {"packages": [...]}.
+func npmWorkspaces(root string, content []byte) ([]string, error) {
+	var manifest struct {
+		Workspaces json.RawMessage `json:"workspaces"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+	if len(manifest.Workspaces) == 0 {
+		return nil, nil
+	}
+
+	var patterns []string
+	if err := json.Unmarshal(manifest.Workspaces, &patterns); err != nil {
+		var object struct {
+			Packages []string `json:"packages"`
+		}
+		if err := json.Unmarshal(manifest.Workspaces, &object); err != nil {
+			return nil, fmt.Errorf("workspaces must be an array or have packages: %w", err)
+		}
+		patterns = object.Packages
+	}
+
+	return matchPackageDirs(root, patterns, "package.json")
+}
+
+// pnpmWorkspaces returns the package directories matching the packages list of
+// a pnpm-workspace.yaml file.
+func pnpmWorkspaces(root string, content []byte) ([]string, error) {
+	var patterns []string
+	inPackages := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "packages:"):
+			inPackages = true
+		case inPackages && strings.HasPrefix(trimmed, "-"):
+			patterns = append(patterns, unquote(strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))))
+		case line[0] != ' ' && line[0] != '\t':
+			inPackages = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return matchPackageDirs(root, patterns, "package.json")
+}
+
+// cargoWorkspaces returns the directories matching the members, minus the
+// excludes, of the [workspace] table of a Cargo.toml file.
+func cargoWorkspaces(root string, content []byte) ([]string, error) {
+	var members, excludes []string
+	var current *[]string
+	inWorkspace := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+
+		if current == nil && strings.HasPrefix(line, "[") {
+			inWorkspace = line == "[workspace]"
+			continue
+		}
+		if !inWorkspace {
+			continue
+		}
+
+		if current == nil {
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			switch strings.TrimSpace(key) {
+			case "members":
+				current = &members
+			case "exclude":
+				current = &excludes
+			default:
+				continue
+			}
+			line = strings.TrimPrefix(strings.TrimSpace(value), "[")
+		}
+
+		closed := strings.Contains(line, "]")
+		line, _, _ = strings.Cut(line, "]")
+		for item := range strings.SplitSeq(line, ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				*current = append(*current, item)
+			}
+		}
+		if closed {
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, pattern := range excludes {
+		excludes[i] = "!" + pattern
+	}
+	return matchPackageDirs(root, append(members, excludes...), "Cargo.toml")
+}
+
+// matchPackageDirs returns the sorted directories matching patterns that contain
+// manifest. Patterns starting with "!" exclude directories; node_modules is
+// never searched.
+func matchPackageDirs(root string, patterns []string, manifest string) ([]string, error) {
+	var include, exclude []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			exclude = append(exclude, strings.TrimPrefix(negated, "./"))
+		} else if pattern != "" {
+			include = append(include, pattern+"/"+manifest)
+		}
+	}
+
+	files, err := expandInputs(root, include)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, file := range files {
+		dir := path.Dir(file)
+		if slices.Contains(strings.Split(dir, "/"), "node_modules") {
+			continue
+		}
+		if !slices.ContainsFunc(exclude, func(pattern string) bool { return matchGlob(pattern, dir) }) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+func cleanWorkspaceDir(dir string) string {
+	return path.Clean(strings.TrimPrefix(filepath.ToSlash(dir), "./"))
+}
+
+func unquote(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return strings.Trim(s, `'`)
+}
+
+// ChangedFiles returns the slash-separated paths, relative to the repository
+// root, of the files that differ from HEAD, including untracked files.
+func ChangedFiles(root string) ([]string, error) {
+	diff, err := gitFiles(root, "diff", "--name-only", "-z", "HEAD")
+	if err != nil {
+		// Without commits every tracked file is new.
+		if diff, err = gitFiles(root, "ls-files", "-z"); err != nil {
+			return nil, err
+		}
+	}
+
+	untracked, err := gitFiles(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	files := append(diff, untracked...)
+	sort.Strings(files)
+	return slices.Compact(files), nil
+}
+
+func gitFiles(root string, args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+	for file := range strings.SplitSeq(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// containsChange reports whether any of the changed files is inside dir.
+func containsChange(dir string, changed []string) bool {
+	if dir == "." {
+		return len(changed) > 0
+	}
+	return slices.ContainsFunc(changed, func(file string) bool { return strings.HasPrefix(file, dir+"/") })
+}
+
+// ParseForeach parses a foreach value: "workspace" runs a check in every
+// workspace package, "workspace:KIND" only in packages of one kind.
+func ParseForeach(s string) (kind WorkspaceKind, err error) {
+	rest, ok := strings.CutPrefix(s, "workspace")
+	if !ok || (rest != "" && !strings.HasPrefix(rest, ":")) {
+		return "", fmt.Errorf("unknown foreach %q (want workspace or workspace:KIND)", s)
+	}
+
+	switch kind = WorkspaceKind(strings.TrimPrefix(rest, ":")); kind {
+	case "", WorkspaceGo, WorkspaceNode, WorkspaceCargo:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("unknown workspace kind %q (want go, node or cargo)", kind)
+	}
+}
+
+// ExpandWorkspaces replaces every check running for each workspace with one
+// check per matching package of the repository at root, named "NAME (DIR)" and
+// running in the package directory. Checks needing an expanded check need its
+// instance for the same package, or every instance when there is none.
+func ExpandWorkspaces(checks []Check, root string) ([]Check, error) {
+	if !slices.ContainsFunc(checks, func(c Check) bool { return c.Foreach }) {
+		return checks, nil
+	}
+
+	workspaces, err := DiscoverWorkspaces(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	if slices.ContainsFunc(checks, func(c Check) bool { return c.Foreach && c.ChangedOnly }) {
+		if changed, err = ChangedFiles(root); err != nil {
+			return nil, err
+		}
+	}
+
+	instances := make(map[string][]Check)
+	expanded := make([]Check, 0, len(checks))
+	for _, check := range checks {
+		if !check.Foreach {
+			expanded = append(expanded, check)
+			continue
+		}
+
+		// A check expanding to no package, e.g. with changedOnly and nothing
+		// changed, still satisfies the checks that need it.
+		if _, ok := instances[check.Name]; !ok {
+			instances[check.Name] = nil
+		}
+
+		seen := make(map[string]bool)
+		for _, ws := range workspaces {
+			if (check.WorkspaceKind != "" && ws.Kind != check.WorkspaceKind) || seen[ws.Dir] {
+				continue
+			}
+			if check.ChangedOnly && !containsChange(ws.Dir, changed) {
+				continue
+			}
+			seen[ws.Dir] = true
+
+			instance := check
+			instance.Name = fmt.Sprintf("%s (%s)", check.Name, ws.Dir)
+			instance.Foreach = false
+			instance.Package = ws.Dir
+			if !filepath.IsAbs(check.Dir) {
+				instance.Dir = filepath.Join(root, filepath.FromSlash(ws.Dir), check.Dir)
+			}
+			instances[check.Name] = append(instances[check.Name], instance)
+			expanded = append(expanded, instance)
+		}
+	}
+
+	for i, check := range expanded {
+		expanded[i].Needs = expandNeeds(check, instances)
+	}
+
+	return expanded, ValidateNeeds(expanded)
+}
+
+func expandNeeds(check Check, instances map[string][]Check) []string {
+	var needs []string
+	for _, name := range check.Needs {
+		expanded, ok := instances[name]
+		if !ok {
+			needs = append(needs, name)
+			continue
+		}
+
+		if i := slices.IndexFunc(expanded, func(c Check) bool { return c.Package == check.Package }); i >= 0 {
+			needs = append(needs, expanded[i].Name)
+			continue
+		}
+		for _, instance := range expanded {
+			needs = append(needs, instance.Name)
+		}
+	}
+	return needs
+}
+
+// GroupByPackage orders results by workspace package, keeping the order of
+// first appearance. Results outside any package come first.
+func GroupByPackage(results []Result) []Result {
+	order := map[string]int{"": 0}
+	for _, r := range results {
+		if _, ok := order[r.Package]; !ok {
+			order[r.Package] = len(order)
+		}
+	}
+
+	grouped := slices.Clone(results)
+	sort.SliceStable(grouped, func(i, j int) bool { return order[grouped[i].Package] < order[grouped[j].Package] })
+	return grouped
+}
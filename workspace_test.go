@@ -0,0 +1,261 @@
+package blocc
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverWorkspaces(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []Workspace
+	}{
+		{
+			name: "go.work",
+			files: map[string]string{
+				"go.work": "go 1.24\n\nuse ./tools // generators\n\nuse (\n\t.\n\t./services/api\n\t\"./services/web\"\n)\n",
+			},
+			want: []Workspace{
+				{Dir: ".", Kind: WorkspaceGo},
+				{Dir: "services/api", Kind: WorkspaceGo},
+				{Dir: "services/web", Kind: WorkspaceGo},
+				{Dir: "tools", Kind: WorkspaceGo},
+			},
+		},
+		{
+			name: "npm workspaces",
+			files: map[string]string{
+				"package.json":                                `{"name": "root", "workspaces": ["packages/*", "!packages/legacy"]}`,
+				"packages/api/package.json":                   `{}`,
+				"packages/legacy/package.json":                `{}`,
+				"packages/api/node_modules/dep/package.json":  `{}`,
+				"packages/notes/README.md":                    "",
+				"packages/web/package.json":                   `{}`,
+				"packages/web/node_modules/x/package.json":    `{}`,
+				"packages/web/src/fixtures/a/package.json":    `{}`,
+				"packages/web/src/fixtures/a/node_modules/.x": "",
+			},
+			want: []Workspace{{Dir: "packages/api", Kind: WorkspaceNode}, {Dir: "packages/web", Kind: WorkspaceNode}},
+		},
+		{
+			name: "yarn packages object",
+			files: map[string]string{
+				"package.json":          `{"workspaces": {"packages": ["apps/**"]}}`,
+				"apps/a/package.json":   `{}`,
+				"apps/b/c/package.json": `{}`,
+			},
+			want: []Workspace{{Dir: "apps/a", Kind: WorkspaceNode}, {Dir: "apps/b/c", Kind: WorkspaceNode}},
+		},
+		{
+			name: "pnpm",
+			files: map[string]string{
+				"pnpm-workspace.yaml": "packages:\n  - 'apps/*'\n  - \"libs/*\" # shared\n  - '!libs/old'\n" +
+					"catalog:\n  - nope\n",
+				"apps/site/package.json":  `{}`,
+				"libs/ui/package.json":    `{}`,
+				"libs/old/package.json":   `{}`,
+				"nope/package.json":       `{}`,
+				"apps/empty/.gitkeep":     "",
+				"libs/ui/nested/file.txt": "",
+			},
+			want: []Workspace{{Dir: "apps/site", Kind: WorkspaceNode}, {Dir: "libs/ui", Kind: WorkspaceNode}},
+		},
+		{
+			name: "cargo",
+			files: map[string]string{
+				"Cargo.toml": "[package]\nname = \"root\"\n\n[workspace]\nmembers = [\n  \"crates/*\", # all crates\n" +
+					"  \"cli\",\n]\nexclude = [\"crates/scratch\"]\n\n[dependencies]\nmembers = [\"nope\"]\n",
+				"crates/core/Cargo.toml":    "",
+				"crates/scratch/Cargo.toml": "",
+				"cli/Cargo.toml":            "",
+				"nope/Cargo.toml":           "",
+			},
+			want: []Workspace{
+				{Dir: "cli", Kind: WorkspaceCargo},
+				{Dir: "crates/core", Kind: WorkspaceCargo},
+			},
+		},
+		{name: "no workspaces", files: map[string]string{"package.json": `{"name": "app"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			got, err := DiscoverWorkspaces(dir)
+			if err != nil {
+				t.Fatalf("DiscoverWorkspaces() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiscoverWorkspaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"package.json": `{"workspaces": "packages/*"}`})
+	if _, err := DiscoverWorkspaces(dir); err == nil || !strings.Contains(err.Error(), "package.json") {
+		t.Errorf("DiscoverWorkspaces() error = %v, want invalid package.json", err)
+	}
+}
+
+func TestParseForeach(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    WorkspaceKind
+		wantErr string
+	}{
+		{input: "workspace"},
+		{input: "workspace:go", want: WorkspaceGo},
+		{input: "workspace:cargo", want: WorkspaceCargo},
+		{input: "workspace:python", wantErr: "unknown workspace kind"},
+		{input: "workspaces", wantErr: "unknown foreach"},
+		{input: "file", wantErr: "unknown foreach"},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseForeach(tt.input)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseForeach(%q) error = %v, want contains %q", tt.input, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseForeach(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandWorkspaces(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.work":             "use (\n\t./api\n\t./web\n)\n",
+		"package.json":        `{"workspaces": ["web"]}`,
+		"web/package.json":    `{}`,
+		"api/go.mod":          "module api\n",
+		"web/go.mod":          "module web\n",
+		"docs/index.markdown": "",
+	})
+
+	checks := []Check{
+		{Name: "generate", Command: "go generate ./..."},
+		{Name: "build", Command: "go build ./...", Foreach: true, WorkspaceKind: WorkspaceGo, Needs: []string{"generate"}},
+		{Name: "test", Command: "go test ./...", Foreach: true, Dir: "internal", Needs: []string{"build"}},
+		{Name: "report", Command: "true", Needs: []string{"test"}},
+	}
+
+	got, err := ExpandWorkspaces(checks, root)
+	if err != nil {
+		t.Fatalf("ExpandWorkspaces() error = %v", err)
+	}
+
+	want := []struct {
+		name, pkg, dir string
+		needs          []string
+	}{
+		{"generate", "", "", nil},
+		{"build (api)", "api", root + "/api", []string{"generate"}},
+		{"build (web)", "web", root + "/web", []string{"generate"}},
+		{"test (api)", "api", root + "/api/internal", []string{"build (api)"}},
+		{"test (web)", "web", root + "/web/internal", []string{"build (web)"}},
+		{"report", "", "", []string{"test (api)", "test (web)"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExpandWorkspaces() = %+v, want %d checks", got, len(want))
+	}
+	for i, w := range want {
+		c := got[i]
+		if c.Name != w.name || c.Package != w.pkg || c.Dir != w.dir || !reflect.DeepEqual(c.Needs, w.needs) || c.Foreach {
+			t.Errorf("check %d = %+v, want %+v", i, c, w)
+		}
+	}
+
+	plain := []Check{{Name: "lint", Command: "make lint"}}
+	if got, err := ExpandWorkspaces(plain, t.TempDir()); err != nil || !reflect.DeepEqual(got, plain) {
+		t.Errorf("ExpandWorkspaces() = %+v, %v, want checks unchanged", got, err)
+	}
+}
+
+func TestExpandWorkspacesNoPackages(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"go.work": "use ./api\n", "api/go.mod": "module api\n"})
+
+	checks := []Check{
+		{Name: "build", Command: "npm run build", Foreach: true, WorkspaceKind: WorkspaceNode},
+		{Name: "test", Command: "npm test", Needs: []string{"build"}},
+		{Name: "e2e", Command: "npm run e2e", Foreach: true, WorkspaceKind: WorkspaceNode, Needs: []string{"build"}},
+	}
+	got, err := ExpandWorkspaces(checks, root)
+	if err != nil {
+		t.Fatalf("ExpandWorkspaces() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "test" || len(got[0].Needs) != 0 {
+		t.Errorf("ExpandWorkspaces() = %+v, want test without needs", got)
+	}
+}
+
+func TestExpandWorkspacesChangedOnly(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.work":     "use (\n\t./api\n\t./web\n\t./cli\n)\n",
+		"api/main.go": "package main\n",
+		"web/main.go": "package main\n",
+		"cli/main.go": "package main\n",
+	})
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	writeFiles(t, root, map[string]string{"api/main.go": "package main // changed\n", "cli/new.go": "package main\n"})
+
+	checks := []Check{{Name: "vet", Command: "go vet ./...", Foreach: true, ChangedOnly: true}}
+	got, err := ExpandWorkspaces(checks, root)
+	if err != nil {
+		t.Fatalf("ExpandWorkspaces() error = %v", err)
+	}
+
+	var names []string
+	for _, c := range got {
+		names = append(names, c.Name)
+	}
+	if want := []string{"vet (api)", "vet (cli)"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ExpandWorkspaces() = %v, want %v", names, want)
+	}
+}
+
+func TestGroupByPackage(t *testing.T) {
+	results := []Result{
+		{Name: "lint (web)", Package: "web"},
+		{Name: "lint (api)", Package: "api"},
+		{Name: "generate"},
+		{Name: "test (web)", Package: "web"},
+		{Name: "test (api)", Package: "api"},
+	}
+
+	var names []string
+	for _, r := range GroupByPackage(results) {
+		names = append(names, r.Name)
+	}
+
+	want := []string{"generate", "lint (web)", "test (web)", "lint (api)", "test (api)"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("GroupByPackage() = %v, want %v", names, want)
+	}
+	if results[0].Name != "lint (web)" {
+		t.Error("GroupByPackage() modified its argument")
+	}
+}